@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/server"
+	"github.com/leo-the-nardo/chatservice/internal/infra/webserver"
+	"log/slog"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultShutdownTimeout = 25 * time.Second
+
+// handlerGracePeriod is how long handlers cancelled when the drain timed out
+// get to save their partial turns before the database is closed.
+const handlerGracePeriod = 5 * time.Second
+
+// lifecycle runs the servers until a termination signal arrives or one of them
+// fails, then drains both and closes the database once their handlers returned.
+type lifecycle struct {
+	grpcServer      *server.GRPCServer
+	webServer       *webserver.WebServer
+	db              *sql.DB
+	shutdownTimeout time.Duration
+	logger          *slog.Logger
+}
+
+func (this *lifecycle) run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, 2)
+	go func() {
+		err := this.grpcServer.Start()
+		if err != nil {
+			failed <- errors.New("grpc server:" + err.Error())
+		}
+	}()
+	go func() {
+		err := this.webServer.Start()
+		if err != nil {
+			failed <- errors.New("http server:" + err.Error())
+		}
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		this.logger.Info("shutting down", "timeout", this.shutdownTimeout.String())
+	case runErr = <-failed:
+		this.logger.Error("server failed, shutting down", "error", runErr)
+	}
+	// a second signal falls back to the default behaviour and kills the process
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), this.shutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		err := this.grpcServer.Stop(shutdownCtx)
+		if err != nil {
+			this.logger.Error("grpc server did not drain in time", "error", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		err := this.webServer.Shutdown(shutdownCtx)
+		if err != nil {
+			this.logger.Error("http server did not drain in time", "error", err)
+		}
+	}()
+	wg.Wait()
+
+	graceCtx, cancelGrace := context.WithTimeout(context.Background(), handlerGracePeriod)
+	defer cancelGrace()
+	err := this.grpcServer.WaitHandlers(graceCtx)
+	if err != nil {
+		this.logger.Error("grpc handlers still running, closing the database anyway", "error", err)
+	}
+	err = this.webServer.WaitHandlers(graceCtx)
+	if err != nil {
+		this.logger.Error("http handlers still running, closing the database anyway", "error", err)
+	}
+
+	err = this.db.Close()
+	if err != nil {
+		this.logger.Error("failed to close database", "error", err)
+	}
+	this.logger.Info("shutdown complete")
+	return runErr
+}
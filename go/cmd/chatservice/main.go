@@ -5,17 +5,43 @@ import (
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/leo-the-nardo/chatservice/configs"
+	"github.com/leo-the-nardo/chatservice/internal/application/chatturn"
+	"github.com/leo-the-nardo/chatservice/internal/application/limiter"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/authenticateapikey"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/cancelgeneration"
 	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletion"
 	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/closechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/createapikey"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/deletechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/editmessage"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getchat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getusage"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listchats"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listmessages"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/regenerateanswer"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/reopenchat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/revokeapikey"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"github.com/leo-the-nardo/chatservice/internal/infra/generation"
 	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/server"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/service"
+	"github.com/leo-the-nardo/chatservice/internal/infra/llm"
+	"github.com/leo-the-nardo/chatservice/internal/infra/quota"
 	"github.com/leo-the-nardo/chatservice/internal/infra/repository"
 	"github.com/leo-the-nardo/chatservice/internal/infra/web"
 	"github.com/leo-the-nardo/chatservice/internal/infra/webserver"
 	"github.com/sashabaranov/go-openai"
+	"log/slog"
+	"net/http"
+	"os"
 )
 
 func main() {
 	config := configs.LoadConfig(".")
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	dbConn, err := sql.Open(
 		config.DBDriver,
 		fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&multiStatements=true",
@@ -29,44 +55,183 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	defer dbConn.Close()
 
-	repo := repository.NewChatRepository(dbConn)
-	client := openai.NewClient(config.OpenAIApiKey)
+	modelConfigs, err := configs.LoadModels(config.ModelsFile)
+	if err != nil {
+		panic(err)
+	}
+	var modelSpecs []entity.ModelSpec
+	for _, model := range modelConfigs {
+		modelSpecs = append(modelSpecs, entity.ModelSpec{
+			Name:            model.Name,
+			ContextWindow:   model.ContextWindow,
+			MaxOutputTokens: model.MaxOutputTokens,
+			Encoding:        model.Encoding,
+			PromptPrice:     model.PromptPricePer1K,
+			CompletionPrice: model.CompletionPricePer1K,
+		})
+	}
+	models, err := entity.NewModelRegistry(modelSpecs)
+	if err != nil {
+		panic(err)
+	}
+	// new chats use the default model unless the client overrides it
+	_, err = models.Get(config.Model)
+	if err != nil {
+		panic(err)
+	}
 
-	chatConfig := chatcompletion.ConfigInputDTO{
-		Model:                config.Model,
-		ModelMaxTokens:       config.ModelMaxTokens,
-		Temperature:          float32(config.Temperature),
-		TopP:                 float32(config.TopP),
-		N:                    config.N,
-		Stop:                 config.Stop,
-		MaxTokens:            config.MaxTokens,
-		InitialSystemMessage: config.InitialChatMessage,
+	repo := repository.NewChatRepository(dbConn, models)
+	usageRepo := repository.NewUsageRepository(dbConn)
+	var quotaGateway gateway.QuotaGateway
+	switch config.QuotaStore {
+	case "", "memory":
+		quotaGateway = quota.NewMemoryStore()
+	case "sql":
+		quotaGateway = repository.NewQuotaRepository(dbConn)
+	default:
+		panic("unknown QUOTA_STORE: " + config.QuotaStore)
+	}
+	quotas, err := configs.LoadQuotas(config.QuotasFile)
+	if err != nil {
+		panic(err)
+	}
+	var quotaTiers []*entity.QuotaTier
+	for _, tier := range quotas.Tiers {
+		quotaTiers = append(quotaTiers, &entity.QuotaTier{
+			Name:              tier.Name,
+			RequestsPerMinute: tier.RequestsPerMinute,
+			TokensPerDay:      tier.TokensPerDay,
+		})
 	}
-	chatConfigStream := chatcompletionstream.ConfigInputDTO{
+	userTiers := make(map[string]string, len(quotas.Users))
+	for _, user := range quotas.Users {
+		userTiers[user.UserID] = user.Tier
+	}
+	quotaLimiter, err := limiter.NewLimiter(quotaGateway, quotaTiers, userTiers, quotas.DefaultTier)
+	if err != nil {
+		panic(err)
+	}
+	var llmGateway gateway.LLMGateway
+	switch config.LLMProvider {
+	case "", "openai":
+		llmGateway = llm.NewOpenAIProvider(openai.NewClient(config.OpenAIApiKey))
+	case "fake":
+		llmGateway = llm.NewFakeProvider(config.FakeLLMReply)
+	default:
+		panic("unknown LLM_PROVIDER: " + config.LLMProvider)
+	}
+
+	chatConfig := chatturn.ConfigInputDTO{
 		Model:                config.Model,
-		ModelMaxTokens:       config.ModelMaxTokens,
 		Temperature:          float32(config.Temperature),
 		TopP:                 float32(config.TopP),
 		N:                    config.N,
 		Stop:                 config.Stop,
 		MaxTokens:            config.MaxTokens,
 		InitialSystemMessage: config.InitialChatMessage,
+		TruncationStrategy:   config.TruncationStrategy,
+		KeepLastTurns:        config.KeepLastTurns,
+		ReserveReplyTokens:   config.ReserveReplyTokens,
+		Summarize:            config.Summarize,
 	}
 
-	useCase := chatcompletion.NewChatCompletionUseCase(repo, client)
+	useCase := chatcompletion.NewChatCompletionUseCase(repo, llmGateway, models, usageRepo, quotaLimiter)
 
-	streamChannel := make(chan chatcompletionstream.OutputDTO)
-	useCaseStream := chatcompletionstream.NewChatCompletionUseCase(repo, client, streamChannel)
+	generations := generation.NewMemoryRegistry()
+	useCaseStream := chatcompletionstream.NewChatCompletionUseCase(repo, llmGateway, models, usageRepo, quotaLimiter, generations)
+	getChatUseCase := getchat.NewGetChatUseCase(repo)
+	listMessagesUseCase := listmessages.NewListMessagesUseCase(repo)
+	listChatsUseCase := listchats.NewListChatsUseCase(repo)
+	closeChatUseCase := closechat.NewCloseChatUseCase(repo)
+	reopenChatUseCase := reopenchat.NewReopenChatUseCase(repo)
+	deleteChatUseCase := deletechat.NewDeleteChatUseCase(repo)
+	cancelGenerationUseCase := cancelgeneration.NewCancelGenerationUseCase(generations)
+	regenerateAnswerUseCase := regenerateanswer.NewRegenerateAnswerUseCase(useCaseStream)
+	editMessageUseCase := editmessage.NewEditMessageUseCase(useCaseStream)
+	getUsageUseCase := getusage.NewGetUsageUseCase(usageRepo)
 
-	fmt.Println("GRPC server running on port " + config.GRPCServerPort)
-	grpcServer := server.NewGRPCServer(*useCaseStream, chatConfigStream, config.GRPCServerPort, config.AuthToken, streamChannel)
-	go grpcServer.Start()
-	app := webserver.NewWebServer(":" + config.WebServerPort)
-	chatGPTHandler := web.NewWebChatGPTHandler(useCase, chatConfig, config.AuthToken)
-	app.AddHandler("/chat", chatGPTHandler.Handle)
+	keySet := auth.NewKeySet()
+	if config.JWKSFile != "" {
+		keySet, err = auth.LoadKeySet(config.JWKSFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if config.JWTSecret != "" {
+		keySet.AddSecret("", []byte(config.JWTSecret))
+	}
+	if keySet.Len() == 0 {
+		panic("no JWT verification key: set JWKS_FILE or JWT_SECRET")
+	}
+	apiKeyRepo := repository.NewAPIKeyRepository(dbConn)
+	createAPIKeyUseCase := createapikey.NewCreateAPIKeyUseCase(apiKeyRepo)
+	revokeAPIKeyUseCase := revokeapikey.NewRevokeAPIKeyUseCase(apiKeyRepo)
+	authenticator := auth.NewAuthenticator(
+		auth.NewJWTVerifier(keySet, config.JWTIssuer, config.JWTAudience),
+		authenticateapikey.NewAuthenticateAPIKeyUseCase(apiKeyRepo),
+	)
 
+	chatService := service.NewChatService(
+		*useCaseStream,
+		useCase,
+		chatConfig,
+		getChatUseCase,
+		listMessagesUseCase,
+		listChatsUseCase,
+		closeChatUseCase,
+		reopenChatUseCase,
+		deleteChatUseCase,
+		cancelGenerationUseCase,
+		regenerateAnswerUseCase,
+		editMessageUseCase,
+		logger,
+	)
+	grpcServer := server.NewGRPCServer(chatService, config.GRPCServerPort, authenticator, logger)
+	webServer := webserver.NewWebServer(":" + config.WebServerPort)
+	webServer.AddMiddleware(authenticator.Middleware)
+	chatGPTHandler := web.NewWebChatGPTHandler(useCase, chatConfig)
+	webServer.AddHandler("/chat", auth.RequireScope(entity.ScopeChatWrite, chatGPTHandler.Handle))
+	chatStreamHandler := web.NewWebChatStreamHandler(
+		useCaseStream,
+		cancelGenerationUseCase,
+		regenerateAnswerUseCase,
+		editMessageUseCase,
+		chatConfig,
+	)
+	webServer.AddMethodHandler(http.MethodPost, "/chat/stream", auth.RequireScope(entity.ScopeChatWrite, chatStreamHandler.Handle))
+	webServer.AddMethodHandler(http.MethodDelete, "/generations/{id}", auth.RequireScope(entity.ScopeChatWrite, chatStreamHandler.Cancel))
+	webServer.AddMethodHandler(http.MethodPost, "/chats/{id}/regenerate", auth.RequireScope(entity.ScopeChatWrite, chatStreamHandler.Regenerate))
+	webServer.AddMethodHandler(http.MethodPut, "/chats/{id}/messages/{message_id}", auth.RequireScope(entity.ScopeChatWrite, chatStreamHandler.EditMessage))
+	chatHandler := web.NewWebChatHandler(getChatUseCase, listMessagesUseCase, listChatsUseCase)
+	webServer.AddMethodHandler(http.MethodGet, "/chats/{id}", auth.RequireScope(entity.ScopeChatRead, chatHandler.GetChat))
+	webServer.AddMethodHandler(http.MethodGet, "/chats/{id}/messages", auth.RequireScope(entity.ScopeChatRead, chatHandler.ListMessages))
+	webServer.AddMethodHandler(http.MethodGet, "/users/{user_id}/chats", auth.RequireScope(entity.ScopeChatRead, chatHandler.ListChats))
+	chatStatusHandler := web.NewWebChatStatusHandler(closeChatUseCase, reopenChatUseCase, deleteChatUseCase)
+	webServer.AddMethodHandler(http.MethodPost, "/chats/{id}/close", auth.RequireScope(entity.ScopeChatWrite, chatStatusHandler.Close))
+	webServer.AddMethodHandler(http.MethodPost, "/chats/{id}/reopen", auth.RequireScope(entity.ScopeChatWrite, chatStatusHandler.Reopen))
+	webServer.AddMethodHandler(http.MethodDelete, "/chats/{id}", auth.RequireScope(entity.ScopeChatWrite, chatStatusHandler.Delete))
+	usageHandler := web.NewWebUsageHandler(getUsageUseCase)
+	webServer.AddMethodHandler(http.MethodGet, "/users/{user_id}/usage", auth.RequireScope(entity.ScopeChatRead, usageHandler.GetUsage))
+	apiKeyHandler := web.NewWebAPIKeyHandler(createAPIKeyUseCase, revokeAPIKeyUseCase)
+	webServer.AddMethodHandler(http.MethodPost, "/api-keys", auth.RequireScope(entity.ScopeAdmin, apiKeyHandler.Create))
+	webServer.AddMethodHandler(http.MethodDelete, "/api-keys/{id}", auth.RequireScope(entity.ScopeAdmin, apiKeyHandler.Revoke))
+
+	shutdownTimeout := config.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	fmt.Println("GRPC server running on port " + config.GRPCServerPort)
 	fmt.Println("http server running on port " + config.WebServerPort)
-	app.Start()
+	app := &lifecycle{
+		grpcServer:      grpcServer,
+		webServer:       webServer,
+		db:              dbConn,
+		shutdownTimeout: shutdownTimeout,
+		logger:          logger,
+	}
+	err = app.run()
+	if err != nil {
+		panic(err)
+	}
 }
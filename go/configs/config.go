@@ -1,26 +1,41 @@
 package configs
 
-import "github.com/spf13/viper"
+import (
+	"github.com/spf13/viper"
+	"time"
+)
 
 type Config struct {
-	DBDriver           string   `mapstructure:"DB_DRIVER"`
-	DBHost             string   `mapstructure:"DB_HOST"`
-	DBPort             string   `mapstructure:"DB_PORT"`
-	DBUser             string   `mapstructure:"DB_USER"`
-	DBPassword         string   `mapstructure:"DB_PASSWORD"`
-	DBName             string   `mapstructure:"DB_NAME"`
-	WebServerPort      string   `mapstructure:"WEB_SERVER_PORT"`
-	GRPCServerPort     string   `mapstructure:"GRPC_SERVER_PORT"`
-	InitialChatMessage string   `mapstructure:"INITIAL_CHAT_MESSAGE"`
-	OpenAIApiKey       string   `mapstructure:"OPENAI_API_KEY"`
-	Model              string   `mapstructure:"MODEL"`
-	ModelMaxTokens     int      `mapstructure:"MODEL_MAX_TOKENS"`
-	Temperature        float64  `mapstructure:"TEMPERATURE"`
-	TopP               float64  `mapstructure:"TOP_P"`
-	N                  int      `mapstructure:"N"`
-	Stop               []string `mapstructure:"STOP"`
-	MaxTokens          int      `mapstructure:"MAX_TOKENS"`
-	AuthToken          string   `mapstructure:"AUTH_TOKEN"`
+	DBDriver           string        `mapstructure:"DB_DRIVER"`
+	DBHost             string        `mapstructure:"DB_HOST"`
+	DBPort             string        `mapstructure:"DB_PORT"`
+	DBUser             string        `mapstructure:"DB_USER"`
+	DBPassword         string        `mapstructure:"DB_PASSWORD"`
+	DBName             string        `mapstructure:"DB_NAME"`
+	WebServerPort      string        `mapstructure:"WEB_SERVER_PORT"`
+	GRPCServerPort     string        `mapstructure:"GRPC_SERVER_PORT"`
+	InitialChatMessage string        `mapstructure:"INITIAL_CHAT_MESSAGE"`
+	OpenAIApiKey       string        `mapstructure:"OPENAI_API_KEY"`
+	LLMProvider        string        `mapstructure:"LLM_PROVIDER"`
+	FakeLLMReply       string        `mapstructure:"FAKE_LLM_REPLY"`
+	Model              string        `mapstructure:"MODEL"`
+	ModelsFile         string        `mapstructure:"MODELS_FILE"`
+	Temperature        float64       `mapstructure:"TEMPERATURE"`
+	TopP               float64       `mapstructure:"TOP_P"`
+	N                  int           `mapstructure:"N"`
+	Stop               []string      `mapstructure:"STOP"`
+	MaxTokens          int           `mapstructure:"MAX_TOKENS"`
+	TruncationStrategy string        `mapstructure:"TRUNCATION_STRATEGY"`
+	KeepLastTurns      int           `mapstructure:"KEEP_LAST_TURNS"`
+	ReserveReplyTokens bool          `mapstructure:"RESERVE_REPLY_TOKENS"`
+	Summarize          bool          `mapstructure:"SUMMARIZE_ERASED_MESSAGES"`
+	QuotaStore         string        `mapstructure:"QUOTA_STORE"`
+	QuotasFile         string        `mapstructure:"QUOTAS_FILE"`
+	JWKSFile           string        `mapstructure:"JWKS_FILE"`
+	JWTSecret          string        `mapstructure:"JWT_SECRET"`
+	JWTIssuer          string        `mapstructure:"JWT_ISSUER"`
+	JWTAudience        string        `mapstructure:"JWT_AUDIENCE"`
+	ShutdownTimeout    time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
 }
 
 func LoadConfig(path string) *Config {
@@ -40,3 +55,69 @@ func LoadConfig(path string) *Config {
 	}
 	return cfg
 }
+
+// ModelConfig is one entry of the models file. Prices are in USD per 1K tokens.
+type ModelConfig struct {
+	Name                 string  `mapstructure:"name"`
+	ContextWindow        int     `mapstructure:"context_window"`
+	MaxOutputTokens      int     `mapstructure:"max_output_tokens"`
+	Encoding             string  `mapstructure:"encoding"`
+	PromptPricePer1K     float64 `mapstructure:"prompt_price_per_1k"`
+	CompletionPricePer1K float64 `mapstructure:"completion_price_per_1k"`
+}
+
+// LoadModels reads the model registry from a YAML file (MODELS_FILE).
+func LoadModels(path string) ([]ModelConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+	var models []ModelConfig
+	err = v.UnmarshalKey("models", &models)
+	if err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+type QuotaTierConfig struct {
+	Name              string `mapstructure:"name"`
+	RequestsPerMinute int    `mapstructure:"requests_per_minute"`
+	TokensPerDay      int    `mapstructure:"tokens_per_day"`
+}
+
+type QuotaUserConfig struct {
+	UserID string `mapstructure:"user_id"`
+	Tier   string `mapstructure:"tier"`
+}
+
+type QuotasConfig struct {
+	DefaultTier string            `mapstructure:"default_tier"`
+	Tiers       []QuotaTierConfig `mapstructure:"tiers"`
+	Users       []QuotaUserConfig `mapstructure:"users"`
+}
+
+// LoadQuotas reads the quota tiers from a YAML file (QUOTAS_FILE). Without a
+// file every user gets a single unlimited tier.
+func LoadQuotas(path string) (*QuotasConfig, error) {
+	if path == "" {
+		return &QuotasConfig{
+			DefaultTier: "unlimited",
+			Tiers:       []QuotaTierConfig{{Name: "unlimited"}},
+		}, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+	var quotas *QuotasConfig
+	err = v.Unmarshal(&quotas)
+	if err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
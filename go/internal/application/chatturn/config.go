@@ -0,0 +1,108 @@
+package chatturn
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+)
+
+type ConfigInputDTO struct {
+	Model                string   `json:"model"`
+	Temperature          float32  `json:"temperature"`
+	TopP                 float32  `json:"top_p"`
+	N                    int      `json:"n"`
+	Stop                 []string `json:"stop"`
+	MaxTokens            int      `json:"max_tokens"`
+	PresencePenalty      float32  `json:"presence_penalty"`
+	FrequencyPenalty     float32  `json:"frequency_penalty"`
+	InitialSystemMessage string   `json:"initial_system_message"`
+	TruncationStrategy   string   `json:"truncation_strategy"`
+	KeepLastTurns        int      `json:"keep_last_turns"`
+	ReserveReplyTokens   bool     `json:"reserve_reply_tokens"`
+	Summarize            bool     `json:"summarize"`
+}
+
+// ConfigOverrideInputDTO holds the settings a client may choose for a new chat.
+// Nil fields keep the server defaults; overrides are ignored on existing chats.
+type ConfigOverrideInputDTO struct {
+	Model            *string  `json:"model"`
+	Temperature      *float32 `json:"temperature"`
+	TopP             *float32 `json:"top_p"`
+	Stop             []string `json:"stop"`
+	MaxTokens        *int     `json:"max_tokens"`
+	PresencePenalty  *float32 `json:"presence_penalty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty"`
+	SystemPrompt     *string  `json:"system_prompt"`
+}
+
+func (this *Store) newChat(
+	userID string,
+	defaults ConfigInputDTO,
+	overrides *ConfigOverrideInputDTO,
+) (*entity.Chat, error) {
+	chatConfig := mergeConfig(defaults, overrides)
+	model, err := this.models.Get(chatConfig.Model)
+	if err != nil {
+		return nil, err
+	}
+	initialMessage, err := entity.NewMessage("system", chatConfig.InitialSystemMessage, model)
+	if err != nil {
+		return nil, errors.New("failed to create initial message:" + err.Error())
+	}
+	truncation, err := entity.NewTruncationStrategy(chatConfig.TruncationStrategy, chatConfig.KeepLastTurns)
+	if err != nil {
+		return nil, err
+	}
+	config := &entity.ChatConfig{
+		Model:              model,
+		Temperature:        chatConfig.Temperature,
+		TopP:               chatConfig.TopP,
+		N:                  chatConfig.N,
+		Stop:               chatConfig.Stop,
+		MaxTokens:          chatConfig.MaxTokens,
+		PresencePenalty:    chatConfig.PresencePenalty,
+		FrequencyPenalty:   chatConfig.FrequencyPenalty,
+		Truncation:         truncation,
+		ReserveReplyTokens: chatConfig.ReserveReplyTokens,
+		Summarize:          chatConfig.Summarize,
+	}
+	chat, err := entity.NewChat(userID, initialMessage, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new chat: %w", err)
+	}
+	return chat, nil
+}
+
+// mergeConfig applies the client overrides; the model registry acts as the
+// allowlist of models when the chat is created.
+func mergeConfig(defaults ConfigInputDTO, overrides *ConfigOverrideInputDTO) ConfigInputDTO {
+	config := defaults
+	if overrides == nil {
+		return config
+	}
+	if overrides.Model != nil {
+		config.Model = *overrides.Model
+	}
+	if overrides.Temperature != nil {
+		config.Temperature = *overrides.Temperature
+	}
+	if overrides.TopP != nil {
+		config.TopP = *overrides.TopP
+	}
+	if overrides.Stop != nil {
+		config.Stop = overrides.Stop
+	}
+	if overrides.MaxTokens != nil {
+		config.MaxTokens = *overrides.MaxTokens
+	}
+	if overrides.PresencePenalty != nil {
+		config.PresencePenalty = *overrides.PresencePenalty
+	}
+	if overrides.FrequencyPenalty != nil {
+		config.FrequencyPenalty = *overrides.FrequencyPenalty
+	}
+	if overrides.SystemPrompt != nil {
+		config.InitialSystemMessage = *overrides.SystemPrompt
+	}
+	return config
+}
@@ -0,0 +1,152 @@
+package chatturn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/leo-the-nardo/chatservice/internal/application/limiter"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+)
+
+// maxSaveAttempts bounds how many times a turn is re-applied on a fresh copy
+// of the chat when another request saved it first.
+const maxSaveAttempts = 3
+
+// Store loads, admits, saves and bills the turns of the completion use cases.
+type Store struct {
+	chatGateway  gateway.ChatGateway
+	models       *entity.ModelRegistry
+	usageGateway gateway.UsageGateway
+	limiter      *limiter.Limiter
+}
+
+func NewStore(
+	chatGateway gateway.ChatGateway,
+	models *entity.ModelRegistry,
+	usageGateway gateway.UsageGateway,
+	limiter *limiter.Limiter,
+) *Store {
+	return &Store{
+		chatGateway:  chatGateway,
+		models:       models,
+		usageGateway: usageGateway,
+		limiter:      limiter,
+	}
+}
+
+// GetOrNewChat loads the chat chatID or builds a new one from the config,
+// reporting it as created. A new chat is only persisted by Admit, once the
+// quota allowed its first turn, so rejected requests leave nothing behind.
+func (this *Store) GetOrNewChat(
+	ctx context.Context,
+	chatID string,
+	userID string,
+	config ConfigInputDTO,
+	overrides *ConfigOverrideInputDTO,
+) (*entity.Chat, bool, error) {
+	chat, err := this.chatGateway.FindById(ctx, chatID)
+	if err != nil {
+		return nil, false, errors.New("failed to get chat by user id:" + err.Error())
+	}
+	if chat != nil {
+		err = chat.CheckOwner(userID)
+		if err != nil {
+			return nil, false, err
+		}
+		return chat, false, nil
+	}
+	chat, err = this.newChat(userID, config, overrides)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create new chat: %w", err)
+	}
+	return chat, true, nil
+}
+
+// Admit checks the quota of the user for a prompt of the chat window and
+// persists the chat when it was created for this turn.
+func (this *Store) Admit(ctx context.Context, chat *entity.Chat, created bool) error {
+	err := this.limiter.Allow(ctx, chat.UserID, chat.TokenUsage)
+	if err != nil {
+		return fmt.Errorf("quota check failed: %w", err)
+	}
+	if !created {
+		return nil
+	}
+	err = this.chatGateway.Create(ctx, chat)
+	if err != nil {
+		return errors.New("failed to persist chat:" + err.Error())
+	}
+	return nil
+}
+
+// Save persists the chat and, on a version conflict, reloads it and replays the
+// turn with apply so concurrent turns are kept instead of overwritten.
+func (this *Store) Save(
+	ctx context.Context,
+	chat *entity.Chat,
+	apply func(chat *entity.Chat) error,
+) (*entity.Chat, error) {
+	for attempt := 1; ; attempt++ {
+		err := this.chatGateway.Save(ctx, chat)
+		var conflict *gateway.ConflictError
+		if !errors.As(err, &conflict) || attempt == maxSaveAttempts {
+			return chat, err
+		}
+		chat, err = this.chatGateway.FindById(ctx, chat.ID)
+		if err != nil {
+			return nil, err
+		}
+		if chat == nil {
+			return nil, entity.ErrChatNotFound
+		}
+		err = apply(chat)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Charge writes the usage ledger entries of the turn, the answer and, when the
+// window was summarized, the summary completion, and charges them to the daily
+// token quota of the user. The summary is billed by the provider even when Save
+// had to replay the turn on a copy that does not keep it.
+func (this *Store) Charge(
+	ctx context.Context,
+	chat *entity.Chat,
+	promptTokens int,
+	completionTokens int,
+	summaryUsage *gateway.LLMUsage,
+) error {
+	var events []*entity.UsageEvent
+	// a generation cancelled before the provider was called costs nothing
+	if promptTokens+completionTokens > 0 {
+		events = append(events, entity.NewUsageEvent(
+			chat.UserID, chat.ID, chat.Config.Model, promptTokens, completionTokens,
+		))
+	}
+	if summaryUsage != nil {
+		events = append(events, entity.NewUsageEvent(
+			chat.UserID, chat.ID, chat.Config.Model, summaryUsage.PromptTokens, summaryUsage.CompletionTokens,
+		))
+	}
+	tokens := 0
+	for _, event := range events {
+		err := this.usageGateway.Record(ctx, event)
+		if err != nil {
+			return err
+		}
+		tokens += event.PromptTokens + event.CompletionTokens
+	}
+	return this.limiter.Consume(ctx, chat.UserID, tokens)
+}
+
+// RecordUsage stores the usage of the turn on its answer, falling back to the
+// local estimates when the provider reports none.
+func RecordUsage(assistant *entity.Message, usage *gateway.LLMUsage, promptTokens int) {
+	if usage == nil {
+		assistant.EstimateUsage(promptTokens)
+		return
+	}
+	assistant.SetUsage(usage.PromptTokens, usage.CompletionTokens)
+}
@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"time"
+)
+
+// Limiter enforces the tier of each user: a fixed one-minute window of
+// requests and a UTC calendar day of tokens.
+type Limiter struct {
+	quotaGateway gateway.QuotaGateway
+	tiers        map[string]*entity.QuotaTier
+	userTiers    map[string]string
+	defaultTier  string
+}
+
+// NewLimiter builds a limiter from the tiers, the tier of some users and the
+// tier of everyone else.
+func NewLimiter(
+	quotaGateway gateway.QuotaGateway,
+	tiers []*entity.QuotaTier,
+	userTiers map[string]string,
+	defaultTier string,
+) (*Limiter, error) {
+	limiter := &Limiter{
+		quotaGateway: quotaGateway,
+		tiers:        make(map[string]*entity.QuotaTier, len(tiers)),
+		userTiers:    userTiers,
+		defaultTier:  defaultTier,
+	}
+	for _, tier := range tiers {
+		if tier.RequestsPerMinute < 0 || tier.TokensPerDay < 0 {
+			return nil, errors.New("quota tier " + tier.Name + " has a negative limit")
+		}
+		limiter.tiers[tier.Name] = tier
+	}
+	if _, ok := limiter.tiers[defaultTier]; !ok {
+		return nil, errors.New("unknown default quota tier " + defaultTier)
+	}
+	for user, tier := range userTiers {
+		if _, ok := limiter.tiers[tier]; !ok {
+			return nil, errors.New("unknown quota tier " + tier + " for user " + user)
+		}
+	}
+	return limiter, nil
+}
+
+// Allow counts a request of userID that sends promptTokens to the model. It
+// returns an *entity.QuotaExceededError when a limit of the user is reached.
+func (this *Limiter) Allow(ctx context.Context, userID string, promptTokens int) error {
+	tier := this.tierOf(userID)
+	now := time.Now().UTC()
+	if tier.TokensPerDay > 0 {
+		day := now.Truncate(24 * time.Hour)
+		used, err := this.quotaGateway.TokensUsed(ctx, userID, day)
+		if err != nil {
+			return err
+		}
+		if used+promptTokens > tier.TokensPerDay {
+			return &entity.QuotaExceededError{
+				UserID:     userID,
+				Limit:      entity.QuotaTokensPerDay,
+				RetryAfter: day.Add(24 * time.Hour).Sub(now),
+			}
+		}
+	}
+	if tier.RequestsPerMinute > 0 {
+		minute := now.Truncate(time.Minute)
+		count, err := this.quotaGateway.IncrementRequests(ctx, userID, minute)
+		if err != nil {
+			return err
+		}
+		if count > tier.RequestsPerMinute {
+			return &entity.QuotaExceededError{
+				UserID:     userID,
+				Limit:      entity.QuotaRequestsPerMinute,
+				RetryAfter: minute.Add(time.Minute).Sub(now),
+			}
+		}
+	}
+	return nil
+}
+
+// Consume charges tokens billed for userID to the current day.
+func (this *Limiter) Consume(ctx context.Context, userID string, tokens int) error {
+	if this.tierOf(userID).TokensPerDay == 0 || tokens <= 0 {
+		return nil
+	}
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	return this.quotaGateway.AddTokens(ctx, userID, day, tokens)
+}
+
+func (this *Limiter) tierOf(userID string) *entity.QuotaTier {
+	if name, ok := this.userTiers[userID]; ok {
+		return this.tiers[name]
+	}
+	return this.tiers[this.defaultTier]
+}
@@ -0,0 +1,193 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"testing"
+	"time"
+)
+
+// fakeQuotaGateway counts per user whatever the window, so a test running
+// across a window boundary stays deterministic; windows are checked apart.
+type fakeQuotaGateway struct {
+	requests map[string]int
+	tokens   map[string]int
+	windows  []time.Time
+}
+
+func newFakeQuotaGateway() *fakeQuotaGateway {
+	return &fakeQuotaGateway{
+		requests: make(map[string]int),
+		tokens:   make(map[string]int),
+	}
+}
+
+func (this *fakeQuotaGateway) IncrementRequests(ctx context.Context, userID string, window time.Time) (int, error) {
+	this.windows = append(this.windows, window)
+	this.requests[userID]++
+	return this.requests[userID], nil
+}
+
+func (this *fakeQuotaGateway) TokensUsed(ctx context.Context, userID string, window time.Time) (int, error) {
+	this.windows = append(this.windows, window)
+	return this.tokens[userID], nil
+}
+
+func (this *fakeQuotaGateway) AddTokens(ctx context.Context, userID string, window time.Time, tokens int) error {
+	this.windows = append(this.windows, window)
+	this.tokens[userID] += tokens
+	return nil
+}
+
+func testTiers() []*entity.QuotaTier {
+	return []*entity.QuotaTier{
+		{Name: "free", RequestsPerMinute: 2, TokensPerDay: 100},
+		{Name: "unlimited"},
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       string
+		tokensUsed   int
+		promptTokens []int
+		// wantLimit is the limit hit by the last call, empty when it passes
+		wantLimit      string
+		wantRetryAfter time.Duration
+		wantRequests   int
+	}{
+		{
+			name:         "requests under the limit",
+			userID:       "user",
+			promptTokens: []int{10, 10},
+			wantRequests: 2,
+		},
+		{
+			name:           "requests over the limit of the minute",
+			userID:         "user",
+			promptTokens:   []int{10, 10, 10},
+			wantLimit:      entity.QuotaRequestsPerMinute,
+			wantRetryAfter: time.Minute,
+			wantRequests:   3,
+		},
+		{
+			name:         "tokens up to the daily limit",
+			userID:       "user",
+			tokensUsed:   90,
+			promptTokens: []int{10},
+			wantRequests: 1,
+		},
+		{
+			name:           "tokens over the daily limit are rejected before counting the request",
+			userID:         "user",
+			tokensUsed:     90,
+			promptTokens:   []int{11},
+			wantLimit:      entity.QuotaTokensPerDay,
+			wantRetryAfter: 24 * time.Hour,
+			wantRequests:   0,
+		},
+		{
+			name:         "users of an unlimited tier",
+			userID:       "vip",
+			tokensUsed:   1000,
+			promptTokens: []int{1000, 1000, 1000},
+			wantRequests: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotaGateway := newFakeQuotaGateway()
+			quotaGateway.tokens[tt.userID] = tt.tokensUsed
+			limiter, err := NewLimiter(quotaGateway, testTiers(), map[string]string{"vip": "unlimited"}, "free")
+			if err != nil {
+				t.Fatalf("NewLimiter: %v", err)
+			}
+
+			var last error
+			for i, promptTokens := range tt.promptTokens {
+				last = limiter.Allow(context.Background(), tt.userID, promptTokens)
+				if i < len(tt.promptTokens)-1 && last != nil {
+					t.Fatalf("call %d: %v", i, last)
+				}
+			}
+			if tt.wantLimit == "" {
+				if last != nil {
+					t.Fatalf("Allow: %v", last)
+				}
+			} else {
+				var exceeded *entity.QuotaExceededError
+				if !errors.As(last, &exceeded) {
+					t.Fatalf("err = %v, want a QuotaExceededError", last)
+				}
+				if exceeded.Limit != tt.wantLimit {
+					t.Errorf("Limit = %s, want %s", exceeded.Limit, tt.wantLimit)
+				}
+				if exceeded.RetryAfter <= 0 || exceeded.RetryAfter > tt.wantRetryAfter {
+					t.Errorf("RetryAfter = %s, want within (0, %s]", exceeded.RetryAfter, tt.wantRetryAfter)
+				}
+			}
+			if quotaGateway.requests[tt.userID] != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", quotaGateway.requests[tt.userID], tt.wantRequests)
+			}
+			for _, window := range quotaGateway.windows {
+				if window.Location() != time.UTC || !window.Equal(window.Truncate(time.Minute)) {
+					t.Errorf("window %s is not a UTC window start", window)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterConsume(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		tokens     int
+		wantTokens int
+	}{
+		{name: "charges a limited user", userID: "user", tokens: 30, wantTokens: 30},
+		{name: "ignores nothing to charge", userID: "user", tokens: 0, wantTokens: 0},
+		{name: "ignores an unlimited user", userID: "vip", tokens: 30, wantTokens: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotaGateway := newFakeQuotaGateway()
+			limiter, err := NewLimiter(quotaGateway, testTiers(), map[string]string{"vip": "unlimited"}, "free")
+			if err != nil {
+				t.Fatalf("NewLimiter: %v", err)
+			}
+			err = limiter.Consume(context.Background(), tt.userID, tt.tokens)
+			if err != nil {
+				t.Fatalf("Consume: %v", err)
+			}
+			if quotaGateway.tokens[tt.userID] != tt.wantTokens {
+				t.Errorf("tokens = %d, want %d", quotaGateway.tokens[tt.userID], tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		tiers       []*entity.QuotaTier
+		userTiers   map[string]string
+		defaultTier string
+		wantErr     bool
+	}{
+		{name: "valid tiers", tiers: testTiers(), userTiers: map[string]string{"vip": "unlimited"}, defaultTier: "free"},
+		{name: "negative limit", tiers: []*entity.QuotaTier{{Name: "free", RequestsPerMinute: -1}}, defaultTier: "free", wantErr: true},
+		{name: "unknown default tier", tiers: testTiers(), defaultTier: "pro", wantErr: true},
+		{name: "unknown user tier", tiers: testTiers(), userTiers: map[string]string{"vip": "pro"}, defaultTier: "free", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLimiter(newFakeQuotaGateway(), tt.tiers, tt.userTiers, tt.defaultTier)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package summarizer
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"strings"
+)
+
+const (
+	instruction = "You maintain the memory of a long conversation. Merge the current summary " +
+		"and the new messages into a single concise summary that keeps every fact, decision " +
+		"and open question the assistant needs to continue the conversation. " +
+		"Answer with the summary only."
+	summaryPrefix = "Summary of the earlier conversation:\n"
+	// maxSummaryTokens bounds the summary completion, and so what a prompt
+	// must leave free in the context window.
+	maxSummaryTokens = 1024
+	tooLongLine      = "[message too long to summarize]"
+)
+
+// Summarizer folds the messages evicted from a chat window into the rolling
+// summary pinned in the chat, for chats created with summarization enabled.
+type Summarizer struct {
+	llmGateway gateway.LLMGateway
+}
+
+func NewSummarizer(llmGateway gateway.LLMGateway) *Summarizer {
+	return &Summarizer{
+		llmGateway: llmGateway,
+	}
+}
+
+// Summarize returns the usage of the summary completions, nil when nothing was
+// summarized or the provider reported no usage. A backlog that does not fit in
+// the context window of the model is summarized in several completions, oldest
+// messages first; on error, the usage of the completions already applied to
+// the chat is returned with it, as the provider billed them.
+func (this *Summarizer) Summarize(ctx context.Context, chat *entity.Chat) (*gateway.LLMUsage, error) {
+	if !chat.Config.Summarize {
+		return nil, nil
+	}
+	var usage *gateway.LLMUsage
+	for {
+		pending := chat.SummaryPendingMessages()
+		if len(pending) == 0 {
+			return usage, nil
+		}
+		batchUsage, err := this.summarizeBatch(ctx, chat, pending)
+		if err != nil {
+			return usage, err
+		}
+		if batchUsage != nil {
+			if usage == nil {
+				usage = &gateway.LLMUsage{}
+			}
+			usage.PromptTokens += batchUsage.PromptTokens
+			usage.CompletionTokens += batchUsage.CompletionTokens
+		}
+	}
+}
+
+// summarizeBatch folds the oldest pending messages that fit in one prompt, at
+// least one, into the summary.
+func (this *Summarizer) summarizeBatch(
+	ctx context.Context,
+	chat *entity.Chat,
+	pending []*entity.Message,
+) (*gateway.LLMUsage, error) {
+	config := *chat.Config
+	config.Temperature = 0
+	config.N = 1
+	config.Stop = nil
+	config.MaxTokens = min(maxSummaryTokens, config.Model.GetMaxTokens()/4)
+
+	prompt, err := buildPrompt(chat, nil)
+	if err != nil {
+		return nil, err
+	}
+	budget := config.Model.GetMaxTokens() - config.MaxTokens - entity.PromptTokens(prompt)
+	var lines []string
+	var sources []*entity.Message
+	for _, message := range pending {
+		line := message.Role + ": " + message.Content + "\n"
+		tokens, err := entity.CountTokens(line, config.Model)
+		if err != nil {
+			return nil, err
+		}
+		// tokens may merge across lines, one per line keeps the sum an upper bound
+		tokens++
+		if tokens > budget {
+			if len(sources) > 0 {
+				break
+			}
+			line = message.Role + ": " + tooLongLine + "\n"
+		}
+		budget -= tokens
+		lines = append(lines, line)
+		sources = append(sources, message)
+	}
+
+	prompt, err = buildPrompt(chat, lines)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := this.llmGateway.CreateChatCompletion(ctx, &config, prompt)
+	if err != nil {
+		return nil, errors.New("failed to create summary:" + err.Error())
+	}
+	summary, err := entity.NewMessage("system", summaryPrefix+resp.Content, chat.Config.Model)
+	if err != nil {
+		return nil, errors.New("failed to create summary message:" + err.Error())
+	}
+	err = chat.ApplySummary(summary, sources)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Usage, nil
+}
+
+func buildPrompt(chat *entity.Chat, lines []string) ([]*entity.Message, error) {
+	var transcript strings.Builder
+	if chat.Summary != nil {
+		transcript.WriteString("Current summary:\n")
+		transcript.WriteString(strings.TrimPrefix(chat.Summary.Content, summaryPrefix))
+		transcript.WriteString("\n\n")
+	}
+	transcript.WriteString("New messages:\n")
+	for _, line := range lines {
+		transcript.WriteString(line)
+	}
+	system, err := entity.NewMessage("system", instruction, chat.Config.Model)
+	if err != nil {
+		return nil, err
+	}
+	user, err := entity.NewMessage("user", transcript.String(), chat.Config.Model)
+	if err != nil {
+		return nil, err
+	}
+	return []*entity.Message{system, user}, nil
+}
@@ -0,0 +1,60 @@
+package authenticateapikey
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"time"
+)
+
+// lastUsedPrecision bounds how often a busy key writes its last use.
+const lastUsedPrecision = time.Minute
+
+type InputDTO struct {
+	Key string `json:"key"`
+}
+
+type OutputDTO struct {
+	KeyID   string   `json:"key_id"`
+	OwnerID string   `json:"owner_id"`
+	Scopes  []string `json:"scopes"`
+}
+
+type UseCase struct {
+	apiKeyGateway gateway.APIKeyGateway
+}
+
+func NewAuthenticateAPIKeyUseCase(apiKeyGateway gateway.APIKeyGateway) *UseCase {
+	return &UseCase{
+		apiKeyGateway: apiKeyGateway,
+	}
+}
+
+// Execute fails with entity.ErrInvalidAPIKey for every rejected key, so callers
+// cannot tell an unknown key from a wrong secret.
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	id, secret, err := entity.ParseAPIKey(input.Key)
+	if err != nil {
+		return nil, err
+	}
+	key, err := this.apiKeyGateway.FindById(ctx, id)
+	if err != nil {
+		return nil, errors.New("failed to get api key by id:" + err.Error())
+	}
+	now := time.Now()
+	if key == nil || !key.Verify(secret) || !key.IsActive(now) {
+		return nil, entity.ErrInvalidAPIKey
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedPrecision {
+		err = this.apiKeyGateway.MarkUsed(ctx, key.ID, now)
+		if err != nil {
+			return nil, errors.New("failed to mark api key as used:" + err.Error())
+		}
+	}
+	return &OutputDTO{
+		KeyID:   key.ID,
+		OwnerID: key.OwnerID,
+		Scopes:  key.Scopes,
+	}, nil
+}
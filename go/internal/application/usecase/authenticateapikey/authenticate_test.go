@@ -0,0 +1,135 @@
+package authenticateapikey
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAPIKeyGateway struct {
+	keys   map[string]*entity.APIKey
+	marked []string
+}
+
+func (this *fakeAPIKeyGateway) Create(ctx context.Context, key *entity.APIKey) error {
+	this.keys[key.ID] = key
+	return nil
+}
+
+func (this *fakeAPIKeyGateway) FindById(ctx context.Context, id string) (*entity.APIKey, error) {
+	return this.keys[id], nil
+}
+
+func (this *fakeAPIKeyGateway) Revoke(ctx context.Context, key *entity.APIKey) error {
+	return nil
+}
+
+func (this *fakeAPIKeyGateway) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	this.marked = append(this.marked, id)
+	return nil
+}
+
+func TestUseCaseExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup changes the stored key and returns the presented one
+		setup      func(key *entity.APIKey, plain string) string
+		wantErr    error
+		wantMarked bool
+	}{
+		{
+			name:       "valid key",
+			setup:      func(key *entity.APIKey, plain string) string { return plain },
+			wantMarked: true,
+		},
+		{
+			name: "valid key used recently",
+			setup: func(key *entity.APIKey, plain string) string {
+				usedAt := time.Now().Add(-time.Second)
+				key.LastUsedAt = &usedAt
+				return plain
+			},
+		},
+		{
+			name:    "missing prefix",
+			setup:   func(key *entity.APIKey, plain string) string { return strings.TrimPrefix(plain, "csk_") },
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name:    "missing secret",
+			setup:   func(key *entity.APIKey, plain string) string { return "csk_" + key.ID },
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name:    "empty secret",
+			setup:   func(key *entity.APIKey, plain string) string { return "csk_" + key.ID + "_" },
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name:    "empty id",
+			setup:   func(key *entity.APIKey, plain string) string { return "csk__secret" },
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name:    "unknown id",
+			setup:   func(key *entity.APIKey, plain string) string { return strings.Replace(plain, key.ID, "unknown", 1) },
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name:    "wrong secret",
+			setup:   func(key *entity.APIKey, plain string) string { return "csk_" + key.ID + "_wrong" },
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name: "expired key",
+			setup: func(key *entity.APIKey, plain string) string {
+				expiresAt := time.Now().Add(-time.Minute)
+				key.ExpiresAt = &expiresAt
+				return plain
+			},
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+		{
+			name: "revoked key",
+			setup: func(key *entity.APIKey, plain string) string {
+				key.Revoke()
+				return plain
+			},
+			wantErr: entity.ErrInvalidAPIKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := time.Now().Add(time.Hour)
+			key, plain, err := entity.NewAPIKey("test", "owner", []string{entity.ScopeChatRead}, &expiresAt)
+			if err != nil {
+				t.Fatalf("NewAPIKey: %v", err)
+			}
+			apiKeyGateway := &fakeAPIKeyGateway{keys: map[string]*entity.APIKey{key.ID: key}}
+			useCase := NewAuthenticateAPIKeyUseCase(apiKeyGateway)
+
+			output, err := useCase.Execute(InputDTO{Key: tt.setup(key, plain)}, context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(apiKeyGateway.marked) != 0 {
+					t.Errorf("a rejected key was marked as used")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if output.KeyID != key.ID || output.OwnerID != "owner" {
+				t.Errorf("output = %+v, want key %s of owner", output, key.ID)
+			}
+			if marked := len(apiKeyGateway.marked) == 1; marked != tt.wantMarked {
+				t.Errorf("marked = %v, want %v", marked, tt.wantMarked)
+			}
+		})
+	}
+}
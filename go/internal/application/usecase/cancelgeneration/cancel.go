@@ -0,0 +1,37 @@
+package cancelgeneration
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+)
+
+type InputDTO struct {
+	GenerationID string `json:"generation_id"`
+	UserID       string `json:"user_id"`
+}
+
+type OutputDTO struct {
+	GenerationID string `json:"generation_id"`
+}
+
+type UseCase struct {
+	generationGateway gateway.GenerationGateway
+}
+
+func NewCancelGenerationUseCase(generationGateway gateway.GenerationGateway) *UseCase {
+	return &UseCase{
+		generationGateway: generationGateway,
+	}
+}
+
+// Execute stops the generation; the streaming request running it saves the
+// partial answer as truncated and reports it as cancelled.
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	err := this.generationGateway.Cancel(ctx, input.GenerationID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &OutputDTO{
+		GenerationID: input.GenerationID,
+	}, nil
+}
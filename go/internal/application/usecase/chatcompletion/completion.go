@@ -3,50 +3,49 @@ package chatcompletion
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/leo-the-nardo/chatservice/internal/application/chatturn"
+	"github.com/leo-the-nardo/chatservice/internal/application/limiter"
+	"github.com/leo-the-nardo/chatservice/internal/application/summarizer"
 	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
 	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
-	openai "github.com/sashabaranov/go-openai"
 )
 
-type ConfigInputDTO struct {
-	Model                string   `json:"model"`
-	ModelMaxTokens       int      `json:"model_max_tokens"`
-	Temperature          float32  `json:"temperature"`
-	TopP                 float32  `json:"top_p"`
-	N                    int      `json:"n"`
-	Stop                 []string `json:"stop"`
-	MaxTokens            int      `json:"max_tokens"`
-	PresencePenalty      float32  `json:"presence_penalty"`
-	FrequencyPenalty     float32  `json:"frequency_penalty"`
-	InitialSystemMessage string   `json:"initial_system_message"`
-}
-
 type InputDTO struct {
-	ChatID      string `json:"chat_id"`
-	UserID      string `json:"user_id"`
-	UserMessage string `json:"user_message"`
-	Config      ConfigInputDTO
+	ChatID      string                           `json:"chat_id"`
+	UserID      string                           `json:"user_id"`
+	UserMessage string                           `json:"user_message"`
+	Overrides   *chatturn.ConfigOverrideInputDTO `json:"config"`
+	Config      chatturn.ConfigInputDTO          `json:"-"`
 }
 
 type OutputDTO struct {
-	ChatID  string `json:"chat_id"`
-	UserID  string `json:"user_id"`
-	Content string `json:"content"`
+	ChatID           string `json:"chat_id"`
+	UserID           string `json:"user_id"`
+	MessageID        string `json:"message_id"`
+	Content          string `json:"content"`
+	FinishReason     string `json:"finish_reason"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
 }
 
 type UseCase struct {
-	chatGateway  gateway.ChatGateway
-	openAiClient *openai.Client
-	stream       chan OutputDTO
+	llmGateway gateway.LLMGateway
+	summarizer *summarizer.Summarizer
+	turns      *chatturn.Store
 }
 
 func NewChatCompletionUseCase(
 	chatGateway gateway.ChatGateway,
-	openAiClient *openai.Client,
+	llmGateway gateway.LLMGateway,
+	models *entity.ModelRegistry,
+	usageGateway gateway.UsageGateway,
+	limiter *limiter.Limiter,
 ) *UseCase {
 	useCase := &UseCase{
-		chatGateway:  chatGateway,
-		openAiClient: openAiClient,
+		llmGateway: llmGateway,
+		summarizer: summarizer.NewSummarizer(llmGateway),
+		turns:      chatturn.NewStore(chatGateway, models, usageGateway, limiter),
 	}
 	return useCase
 }
@@ -55,7 +54,7 @@ func (this *UseCase) Execute(
 	input InputDTO,
 	ctx context.Context,
 ) (*OutputDTO, error) {
-	chat, err := this.getOrCreateChat(input, ctx)
+	chat, created, err := this.turns.GetOrNewChat(ctx, input.ChatID, input.UserID, input.Config, input.Overrides)
 	if err != nil {
 		return nil, err
 	}
@@ -65,98 +64,65 @@ func (this *UseCase) Execute(
 	}
 	err = chat.AddMessage(userMessage)
 	if err != nil {
-		return nil, errors.New("failed to create user message:" + err.Error())
+		return nil, fmt.Errorf("failed to create user message: %w", err)
 	}
-
-	var messages []openai.ChatCompletionMessage
-	for _, msg := range chat.Messages {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	err = this.turns.Admit(ctx, chat, created)
+	if err != nil {
+		return nil, err
+	}
+	summaryUsage, err := this.summarizer.Summarize(ctx, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize erased messages: %w", err)
 	}
-	resp, err := this.openAiClient.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model:            chat.Config.Model.GetName(),
-			Messages:         messages,
-			MaxTokens:        chat.Config.MaxTokens,
-			Temperature:      chat.Config.Temperature,
-			TopP:             chat.Config.TopP,
-			N:                chat.Config.N,
-			Stop:             chat.Config.Stop,
-			PresencePenalty:  chat.Config.PresencePenalty,
-			FrequencyPenalty: chat.Config.FrequencyPenalty,
-		},
-	)
+
+	promptTokens := chat.TokenUsage
+	resp, err := this.llmGateway.CreateChatCompletion(ctx, chat.Config, chat.Messages)
 	if err != nil {
-		return nil, errors.New("failed to create chat completion stream:" + err.Error())
+		return nil, errors.New("failed to create chat completion:" + err.Error())
 	}
-	msgContent := resp.Choices[0].Message.Content
+	msgContent := resp.Content
 
 	assistant, err := entity.NewMessage("assistant", msgContent, chat.Config.Model)
 	if err != nil {
 		return nil, errors.New("failed to create assistant message:" + err.Error())
 	}
+	chatturn.RecordUsage(assistant, resp.Usage, promptTokens)
 
 	err = chat.AddMessage(assistant)
 	if err != nil {
 		return nil, errors.New("failed to add assistant message:" + err.Error())
 	}
 
-	err = this.chatGateway.Save(ctx, chat)
+	// the turn's messages are appended again on a fresh copy after a conflict
+	chat, err = this.turns.Save(ctx, chat, func(chat *entity.Chat) error {
+		err := chat.AddMessage(userMessage)
+		if err != nil {
+			return err
+		}
+		return chat.AddMessage(assistant)
+	})
 	if err != nil {
-		return nil, errors.New("failed to save chat:" + err.Error())
+		return nil, fmt.Errorf("failed to save chat: %w", err)
 	}
-
-	return &OutputDTO{
-		ChatID:  chat.ID,
-		UserID:  input.UserID,
-		Content: msgContent,
-	}, nil
-}
-
-func (this *UseCase) getOrCreateChat(
-	input InputDTO,
-	ctx context.Context,
-) (*entity.Chat, error) {
-	chat, err := this.chatGateway.FindById(ctx, input.ChatID)
+	err = this.turns.Charge(ctx, chat, assistant.PromptTokens, assistant.CompletionTokens, summaryUsage)
 	if err != nil {
-		return nil, errors.New("failed to get chat by user id:" + err.Error())
-	}
-	if chat == nil {
-		chat, err = createNewChat(input)
-		if err != nil {
-			return nil, errors.New("failed to create new chat:" + err.Error())
-		}
-		err = this.chatGateway.Create(ctx, chat)
-		if err != nil {
-			return nil, errors.New("failed to persist chat:" + err.Error())
-		}
+		return nil, errors.New("failed to record usage:" + err.Error())
 	}
-	return chat, nil
 
+	return &OutputDTO{
+		ChatID:           chat.ID,
+		UserID:           input.UserID,
+		MessageID:        assistant.ID,
+		Content:          msgContent,
+		FinishReason:     finishReason(resp.FinishReason),
+		PromptTokens:     assistant.PromptTokens,
+		CompletionTokens: assistant.CompletionTokens,
+	}, nil
 }
 
-func createNewChat(input InputDTO) (*entity.Chat, error) {
-	model := entity.NewModel(input.Config.Model, input.Config.ModelMaxTokens)
-	initialMessage, err := entity.NewMessage("system", input.Config.InitialSystemMessage, model)
-	if err != nil {
-		return nil, errors.New("failed to create initial message:" + err.Error())
-	}
-	config := &entity.ChatConfig{
-		Model:            model,
-		Temperature:      input.Config.Temperature,
-		TopP:             input.Config.TopP,
-		N:                input.Config.N,
-		Stop:             input.Config.Stop,
-		MaxTokens:        input.Config.MaxTokens,
-		PresencePenalty:  input.Config.PresencePenalty,
-		FrequencyPenalty: input.Config.FrequencyPenalty,
-	}
-	chat, err := entity.NewChat(input.UserID, initialMessage, config)
-	if err != nil {
-		return nil, errors.New("failed to create new chat:" + err.Error())
+func finishReason(reason string) string {
+	if reason == "" {
+		return gateway.FinishReasonStop
 	}
-	return chat, nil
+	return reason
 }
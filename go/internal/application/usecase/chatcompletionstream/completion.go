@@ -3,181 +3,226 @@ package chatcompletionstream
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/leo-the-nardo/chatservice/internal/application/chatturn"
+	"github.com/leo-the-nardo/chatservice/internal/application/limiter"
+	"github.com/leo-the-nardo/chatservice/internal/application/summarizer"
 	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
 	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
-	openai "github.com/sashabaranov/go-openai"
 	"io"
 	"strings"
 )
 
-type ConfigInputDTO struct {
-	Model                string   `json:"model"`
-	ModelMaxTokens       int      `json:"model_max_tokens"`
-	Temperature          float32  `json:"temperature"`
-	TopP                 float32  `json:"top_p"`
-	N                    int      `json:"n"`
-	Stop                 []string `json:"stop"`
-	MaxTokens            int      `json:"max_tokens"`
-	PresencePenalty      float32  `json:"presence_penalty"`
-	FrequencyPenalty     float32  `json:"frequency_penalty"`
-	InitialSystemMessage string   `json:"initial_system_message"`
-}
-
+// CumulativeContent also fills Content on every chunk with the answer so far,
+// for clients written before chunks carried deltas.
 type InputDTO struct {
-	ChatID      string `json:"chat_id"`
-	UserID      string `json:"user_id"`
-	UserMessage string `json:"user_message"`
-	Config      ConfigInputDTO
+	ChatID            string                           `json:"chat_id"`
+	UserID            string                           `json:"user_id"`
+	UserMessage       string                           `json:"user_message"`
+	Overrides         *chatturn.ConfigOverrideInputDTO `json:"config"`
+	CumulativeContent bool                             `json:"cumulative_content"`
+	Config            chatturn.ConfigInputDTO          `json:"-"`
 }
 
+// OutputDTO is sent for every chunk with Delta holding the new text. Sequence
+// numbers the chunks from 1; the result returned once the turn is saved takes
+// the next number and is the only one with the whole Content, FinishReason,
+// MessageID and the token counts. GenerationID names the generation for
+// cancellation; it is first sent alone, with Sequence 0, as soon as the
+// generation starts. A cancelled generation has no MessageID when it produced no text.
 type OutputDTO struct {
-	ChatID  string `json:"chat_id"`
-	UserID  string `json:"user_id"`
-	Content string `json:"content"`
+	ChatID           string `json:"chat_id"`
+	UserID           string `json:"user_id"`
+	GenerationID     string `json:"generation_id"`
+	Sequence         int    `json:"sequence"`
+	MessageID        string `json:"message_id,omitempty"`
+	Content          string `json:"content,omitempty"`
+	Delta            string `json:"delta,omitempty"`
+	FinishReason     string `json:"finish_reason,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
 }
 
 type UseCase struct {
-	chatGateway  gateway.ChatGateway
-	openAiClient *openai.Client
-	stream       chan OutputDTO
+	chatGateway       gateway.ChatGateway
+	llmGateway        gateway.LLMGateway
+	summarizer        *summarizer.Summarizer
+	turns             *chatturn.Store
+	generationGateway gateway.GenerationGateway
 }
 
 func NewChatCompletionUseCase(
 	chatGateway gateway.ChatGateway,
-	openAiClient *openai.Client,
-	stream chan OutputDTO,
+	llmGateway gateway.LLMGateway,
+	models *entity.ModelRegistry,
+	usageGateway gateway.UsageGateway,
+	limiter *limiter.Limiter,
+	generationGateway gateway.GenerationGateway,
 ) *UseCase {
 	useCase := &UseCase{
-		chatGateway:  chatGateway,
-		openAiClient: openAiClient,
-		stream:       stream,
+		chatGateway:       chatGateway,
+		llmGateway:        llmGateway,
+		summarizer:        summarizer.NewSummarizer(llmGateway),
+		turns:             chatturn.NewStore(chatGateway, models, usageGateway, limiter),
+		generationGateway: generationGateway,
 	}
 	return useCase
 }
 
+// Execute sends every partial response to stream, which belongs to the caller
+// and must be consumed until Execute returns. The use case never closes it.
 func (this *UseCase) Execute(
 	input *InputDTO,
+	stream chan<- OutputDTO,
 	ctx context.Context,
 ) (*OutputDTO, error) {
-	chat, err := this.getOrCreateChat(input, ctx)
+	conversation, err := this.Open(input, ctx)
 	if err != nil {
 		return nil, err
 	}
-	userMessage, err := entity.NewMessage("user", input.UserMessage, chat.Config.Model)
-	if err != nil {
-		return nil, errors.New("failed to add user message:" + err.Error())
-	}
-	err = chat.AddMessage(userMessage)
+	return conversation.Send(input, stream, ctx)
+}
+
+// complete answers the chat, whose window must end with the message to answer,
+// and saves the turn. A created chat is persisted once the quota allowed the
+// turn. apply replays the turn on a fresh copy of the chat when
+// another request saved it first; it receives the answer to add, which is nil
+// when the generation was cancelled before producing any text.
+//
+// Once the generation started, a cancellation of ctx or of the generation stops
+// it and the text the client received is saved, marked as truncated. The turn
+// is saved and charged even when ctx is cancelled.
+func (this *UseCase) complete(
+	chat *entity.Chat,
+	created bool,
+	input *InputDTO,
+	apply func(chat *entity.Chat, assistant *entity.Message) error,
+	stream chan<- OutputDTO,
+	ctx context.Context,
+) (*OutputDTO, *entity.Chat, error) {
+	err := this.turns.Admit(ctx, chat, created)
 	if err != nil {
-		return nil, errors.New("failed to create user message:" + err.Error())
+		return nil, nil, err
 	}
-
-	var messages []openai.ChatCompletionMessage
-	for _, msg := range chat.Messages {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	// the generation starts before the summary, so it can already be cancelled
+	generationID, generationCtx, done := this.generationGateway.Start(ctx, chat.UserID)
+	defer done()
+	truncated := false
+	select {
+	case stream <- OutputDTO{ChatID: chat.ID, UserID: chat.UserID, GenerationID: generationID}:
+	case <-generationCtx.Done():
+		truncated = true
 	}
-	resp, err := this.openAiClient.CreateChatCompletionStream(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:            chat.Config.Model.GetName(),
-			Messages:         messages,
-			MaxTokens:        chat.Config.MaxTokens,
-			Temperature:      chat.Config.Temperature,
-			TopP:             chat.Config.TopP,
-			N:                chat.Config.N,
-			Stop:             chat.Config.Stop,
-			PresencePenalty:  chat.Config.PresencePenalty,
-			FrequencyPenalty: chat.Config.FrequencyPenalty,
-			Stream:           true,
-		},
-	)
-	if err != nil {
-		return nil, errors.New("failed to create chat completion stream:" + err.Error())
+	var summaryUsage *gateway.LLMUsage
+	if !truncated {
+		summaryUsage, err = this.summarizer.Summarize(generationCtx, chat)
+		if err != nil && generationCtx.Err() == nil {
+			return nil, nil, fmt.Errorf("failed to summarize erased messages: %w", err)
+		}
+		truncated = err != nil
 	}
 
 	var fullResponse strings.Builder
-	for {
-		response, err := resp.Recv()
-		if errors.Is(err, io.EOF) {
-			break
+	var usage *gateway.LLMUsage
+	finishReason := gateway.FinishReasonStop
+	sequence := 0
+	promptTokens := 0
+	if !truncated {
+		promptTokens = chat.TokenUsage
+		resp, err := this.llmGateway.CreateChatCompletionStream(generationCtx, chat.Config, chat.Messages)
+		if err != nil && generationCtx.Err() == nil {
+			return nil, nil, errors.New("failed to create chat completion stream:" + err.Error())
 		}
 		if err != nil {
-			return nil, errors.New("failed to receive streaming response:" + err.Error())
+			truncated = true
+		} else {
+			defer resp.Close()
 		}
-		fullResponse.WriteString(response.Choices[0].Delta.Content)
-		r := OutputDTO{
-			ChatID:  chat.ID,
-			UserID:  chat.UserID,
-			Content: fullResponse.String(),
+		for !truncated {
+			response, err := resp.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				if generationCtx.Err() != nil {
+					truncated = true
+					break
+				}
+				return nil, nil, errors.New("failed to receive streaming response:" + err.Error())
+			}
+			if response.Usage != nil {
+				usage = response.Usage
+			}
+			if response.FinishReason != "" {
+				finishReason = response.FinishReason
+			}
+			if response.Content == "" {
+				continue
+			}
+			sequence++
+			r := OutputDTO{
+				ChatID:       chat.ID,
+				UserID:       chat.UserID,
+				GenerationID: generationID,
+				Sequence:     sequence,
+				Delta:        response.Content,
+			}
+			if input.CumulativeContent {
+				r.Content = fullResponse.String() + response.Content
+			}
+			// only what reached the client is kept in a truncated answer
+			select {
+			case stream <- r:
+				fullResponse.WriteString(response.Content)
+			case <-generationCtx.Done():
+				truncated = true
+			}
 		}
-		this.stream <- r
-	}
-	assistant, err := entity.NewMessage("assistant", fullResponse.String(), chat.Config.Model)
-	if err != nil {
-		return nil, errors.New("failed to create assistant message:" + err.Error())
 	}
 
-	err = chat.AddMessage(assistant)
-	if err != nil {
-		return nil, errors.New("failed to add assistant message:" + err.Error())
+	var assistant *entity.Message
+	if truncated {
+		finishReason = gateway.FinishReasonCancelled
 	}
-
-	err = this.chatGateway.Save(ctx, chat)
-	if err != nil {
-		return nil, errors.New("failed to save chat:" + err.Error())
-	}
-
-	return &OutputDTO{
-		ChatID:  chat.ID,
-		UserID:  chat.UserID,
-		Content: fullResponse.String(),
-	}, nil
-}
-
-func (this *UseCase) getOrCreateChat(
-	input *InputDTO,
-	ctx context.Context,
-) (*entity.Chat, error) {
-	chat, err := this.chatGateway.FindById(ctx, input.ChatID)
-	if err != nil {
-		return nil, errors.New("failed to get chat by user id:" + err.Error())
-	}
-	if chat == nil {
-		chat, err = createNewChat(input)
+	if fullResponse.Len() > 0 {
+		assistant, err = entity.NewMessage("assistant", fullResponse.String(), chat.Config.Model)
 		if err != nil {
-			return nil, errors.New("failed to create new chat:" + err.Error())
+			return nil, nil, errors.New("failed to create assistant message:" + err.Error())
 		}
-		err = this.chatGateway.Create(ctx, chat)
+		assistant.Truncated = truncated
+		chatturn.RecordUsage(assistant, usage, promptTokens)
+		err = chat.AddMessage(assistant)
 		if err != nil {
-			return nil, errors.New("failed to persist chat:" + err.Error())
+			return nil, nil, errors.New("failed to add assistant message:" + err.Error())
 		}
 	}
-	return chat, nil
 
-}
-
-func createNewChat(input *InputDTO) (*entity.Chat, error) {
-	model := entity.NewModel(input.Config.Model, input.Config.ModelMaxTokens)
-	initialMessage, err := entity.NewMessage("system", input.Config.InitialSystemMessage, model)
+	// the client may be gone, the turn is kept anyway
+	ctx = context.WithoutCancel(ctx)
+	chat, err = this.turns.Save(ctx, chat, func(chat *entity.Chat) error {
+		return apply(chat, assistant)
+	})
 	if err != nil {
-		return nil, errors.New("failed to create initial message:" + err.Error())
+		return nil, nil, fmt.Errorf("failed to save chat: %w", err)
+	}
+	output := &OutputDTO{
+		ChatID:       chat.ID,
+		UserID:       chat.UserID,
+		GenerationID: generationID,
+		Sequence:     sequence + 1,
+		Content:      fullResponse.String(),
+		FinishReason: finishReason,
+		// a prompt sent to the provider is billed even without an answer
+		PromptTokens: promptTokens,
 	}
-	config := &entity.ChatConfig{
-		Model:            model,
-		Temperature:      input.Config.Temperature,
-		TopP:             input.Config.TopP,
-		N:                input.Config.N,
-		Stop:             input.Config.Stop,
-		MaxTokens:        input.Config.MaxTokens,
-		PresencePenalty:  input.Config.PresencePenalty,
-		FrequencyPenalty: input.Config.FrequencyPenalty,
+	if assistant != nil {
+		output.MessageID = assistant.ID
+		output.PromptTokens = assistant.PromptTokens
+		output.CompletionTokens = assistant.CompletionTokens
 	}
-	chat, err := entity.NewChat(input.UserID, initialMessage, config)
+	err = this.turns.Charge(ctx, chat, output.PromptTokens, output.CompletionTokens, summaryUsage)
 	if err != nil {
-		return nil, errors.New("failed to create new chat:" + err.Error())
+		return nil, nil, errors.New("failed to record usage:" + err.Error())
 	}
-	return chat, nil
+	return output, chat, nil
 }
@@ -0,0 +1,182 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+)
+
+// Conversation keeps a chat loaded between turns, so a long-lived stream only
+// reads it again after a failed turn left the copy in memory out of date. It
+// runs one turn at a time and is not safe for concurrent use.
+type Conversation struct {
+	useCase *UseCase
+	chatID  string
+	userID  string
+	chat    *entity.Chat
+	// created is set until the first turn persists the new chat
+	created bool
+}
+
+// Open loads the chat of input, or creates it with the input config when
+// input has no chat id, checking it belongs to the user. A new chat is only
+// persisted by its first turn, once the quota allows it.
+func (this *UseCase) Open(input *InputDTO, ctx context.Context) (*Conversation, error) {
+	chat, created, err := this.turns.GetOrNewChat(ctx, input.ChatID, input.UserID, input.Config, input.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	return &Conversation{
+		useCase: this,
+		chatID:  chat.ID,
+		userID:  chat.UserID,
+		chat:    chat,
+		created: created,
+	}, nil
+}
+
+// OpenExisting loads the chat of input, checking it belongs to the user. Unlike
+// Open it never creates one: a missing chat is entity.ErrChatNotFound.
+func (this *UseCase) OpenExisting(input *InputDTO, ctx context.Context) (*Conversation, error) {
+	conversation := &Conversation{
+		useCase: this,
+		chatID:  input.ChatID,
+		userID:  input.UserID,
+	}
+	_, err := conversation.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (this *Conversation) ChatID() string {
+	return this.chatID
+}
+
+// Send answers input.UserMessage; stream is used as in UseCase.Execute.
+func (this *Conversation) Send(
+	input *InputDTO,
+	stream chan<- OutputDTO,
+	ctx context.Context,
+) (*OutputDTO, error) {
+	chat, err := this.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userMessage, err := entity.NewMessage("user", input.UserMessage, chat.Config.Model)
+	if err != nil {
+		return nil, errors.New("failed to add user message:" + err.Error())
+	}
+	// the window ends with the user message, then the answer, on every copy
+	apply := func(chat *entity.Chat, assistant *entity.Message) error {
+		err := chat.AddMessage(userMessage)
+		if err != nil || assistant == nil {
+			return err
+		}
+		return chat.AddMessage(assistant)
+	}
+	err = chat.AddMessage(userMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user message: %w", err)
+	}
+	return this.complete(chat, input, apply, stream, ctx)
+}
+
+// Regenerate archives the last answer of the chat and answers the user message
+// before it again.
+func (this *Conversation) Regenerate(
+	input *InputDTO,
+	stream chan<- OutputDTO,
+	ctx context.Context,
+) (*OutputDTO, error) {
+	chat, err := this.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	apply := func(chat *entity.Chat, assistant *entity.Message) error {
+		_, err := chat.DropLastAnswer()
+		if err != nil || assistant == nil {
+			return err
+		}
+		return chat.AddMessage(assistant)
+	}
+	_, err = chat.DropLastAnswer()
+	if err != nil {
+		return nil, err
+	}
+	return this.complete(chat, input, apply, stream, ctx)
+}
+
+// Edit replaces the last user message of the chat, messageID, by
+// input.UserMessage, archiving it and its answer, and answers the new version.
+func (this *Conversation) Edit(
+	messageID string,
+	input *InputDTO,
+	stream chan<- OutputDTO,
+	ctx context.Context,
+) (*OutputDTO, error) {
+	chat, err := this.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userMessage, err := entity.NewMessage("user", input.UserMessage, chat.Config.Model)
+	if err != nil {
+		return nil, errors.New("failed to create user message:" + err.Error())
+	}
+	apply := func(chat *entity.Chat, assistant *entity.Message) error {
+		err := chat.EditLastUserMessage(messageID, userMessage)
+		if err != nil || assistant == nil {
+			return err
+		}
+		return chat.AddMessage(assistant)
+	}
+	err = chat.EditLastUserMessage(messageID, userMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit user message: %w", err)
+	}
+	return this.complete(chat, input, apply, stream, ctx)
+}
+
+func (this *Conversation) complete(
+	chat *entity.Chat,
+	input *InputDTO,
+	apply func(chat *entity.Chat, assistant *entity.Message) error,
+	stream chan<- OutputDTO,
+	ctx context.Context,
+) (*OutputDTO, error) {
+	created := this.created
+	// a failed first turn may have persisted the chat already; if not, reading
+	// it again finds nothing
+	this.created = false
+	output, chat, err := this.useCase.complete(chat, created, input, apply, stream, ctx)
+	if err != nil {
+		// the failed turn may be half applied to the copy in memory
+		this.chat = nil
+		return nil, err
+	}
+	this.chat = chat
+	return output, nil
+}
+
+// load returns the chat kept in memory, reading it again when a failed turn
+// discarded it.
+func (this *Conversation) load(ctx context.Context) (*entity.Chat, error) {
+	if this.chat != nil {
+		return this.chat, nil
+	}
+	chat, err := this.useCase.chatGateway.FindById(ctx, this.chatID)
+	if err != nil {
+		return nil, errors.New("failed to get chat by id:" + err.Error())
+	}
+	if chat == nil {
+		return nil, entity.ErrChatNotFound
+	}
+	err = chat.CheckOwner(this.userID)
+	if err != nil {
+		return nil, err
+	}
+	this.chat = chat
+	return chat, nil
+}
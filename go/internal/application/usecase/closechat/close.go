@@ -0,0 +1,52 @@
+package closechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+)
+
+type InputDTO struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type OutputDTO struct {
+	ChatID string `json:"chat_id"`
+	Status string `json:"status"`
+}
+
+type UseCase struct {
+	chatGateway gateway.ChatGateway
+}
+
+func NewCloseChatUseCase(chatGateway gateway.ChatGateway) *UseCase {
+	return &UseCase{
+		chatGateway: chatGateway,
+	}
+}
+
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	chat, err := this.chatGateway.FindById(ctx, input.ChatID)
+	if err != nil {
+		return nil, errors.New("failed to get chat by id:" + err.Error())
+	}
+	if chat == nil {
+		return nil, entity.ErrChatNotFound
+	}
+	err = chat.CheckOwner(input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	chat.Close()
+	err = this.chatGateway.Save(ctx, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save chat: %w", err)
+	}
+	return &OutputDTO{
+		ChatID: chat.ID,
+		Status: chat.Status,
+	}, nil
+}
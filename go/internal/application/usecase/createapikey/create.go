@@ -0,0 +1,57 @@
+package createapikey
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"time"
+)
+
+type InputDTO struct {
+	Name      string     `json:"name"`
+	OwnerID   string     `json:"owner_id"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// OutputDTO.Key is the only time the plain key is ever returned.
+type OutputDTO struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	OwnerID   string     `json:"owner_id"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	Key       string     `json:"key"`
+}
+
+type UseCase struct {
+	apiKeyGateway gateway.APIKeyGateway
+}
+
+func NewCreateAPIKeyUseCase(apiKeyGateway gateway.APIKeyGateway) *UseCase {
+	return &UseCase{
+		apiKeyGateway: apiKeyGateway,
+	}
+}
+
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	key, plain, err := entity.NewAPIKey(input.Name, input.OwnerID, input.Scopes, input.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	err = this.apiKeyGateway.Create(ctx, key)
+	if err != nil {
+		return nil, errors.New("failed to persist api key:" + err.Error())
+	}
+	return &OutputDTO{
+		ID:        key.ID,
+		Name:      key.Name,
+		OwnerID:   key.OwnerID,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		CreatedAt: key.CreatedAt,
+		Key:       plain,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package deletechat
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+)
+
+type InputDTO struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type UseCase struct {
+	chatGateway gateway.ChatGateway
+}
+
+func NewDeleteChatUseCase(chatGateway gateway.ChatGateway) *UseCase {
+	return &UseCase{
+		chatGateway: chatGateway,
+	}
+}
+
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) error {
+	chat, err := this.chatGateway.FindById(ctx, input.ChatID)
+	if err != nil {
+		return errors.New("failed to get chat by id:" + err.Error())
+	}
+	if chat == nil {
+		return entity.ErrChatNotFound
+	}
+	err = chat.CheckOwner(input.UserID)
+	if err != nil {
+		return err
+	}
+	err = this.chatGateway.Delete(ctx, chat.ID)
+	if err != nil {
+		return errors.New("failed to delete chat:" + err.Error())
+	}
+	return nil
+}
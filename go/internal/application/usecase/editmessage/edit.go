@@ -0,0 +1,45 @@
+package editmessage
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+)
+
+type InputDTO struct {
+	ChatID            string `json:"chat_id"`
+	UserID            string `json:"user_id"`
+	MessageID         string `json:"message_id"`
+	Content           string `json:"content"`
+	CumulativeContent bool   `json:"cumulative_content"`
+}
+
+type UseCase struct {
+	completionStreamUseCase *chatcompletionstream.UseCase
+}
+
+func NewEditMessageUseCase(completionStreamUseCase *chatcompletionstream.UseCase) *UseCase {
+	return &UseCase{
+		completionStreamUseCase: completionStreamUseCase,
+	}
+}
+
+// Execute replaces the last user message of the chat by Content, archiving the
+// old version and its answer, and streams the answer to the new version as
+// chatcompletionstream.UseCase.Execute does.
+func (this *UseCase) Execute(
+	input InputDTO,
+	stream chan<- chatcompletionstream.OutputDTO,
+	ctx context.Context,
+) (*chatcompletionstream.OutputDTO, error) {
+	completionInput := &chatcompletionstream.InputDTO{
+		ChatID:            input.ChatID,
+		UserID:            input.UserID,
+		UserMessage:       input.Content,
+		CumulativeContent: input.CumulativeContent,
+	}
+	conversation, err := this.completionStreamUseCase.OpenExisting(completionInput, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conversation.Edit(input.MessageID, completionInput, stream, ctx)
+}
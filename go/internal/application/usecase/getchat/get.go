@@ -0,0 +1,84 @@
+package getchat
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+)
+
+type InputDTO struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type ConfigOutputDTO struct {
+	Model            string   `json:"model"`
+	ModelMaxTokens   int      `json:"model_max_tokens"`
+	Temperature      float32  `json:"temperature"`
+	TopP             float32  `json:"top_p"`
+	N                int      `json:"n"`
+	Stop             []string `json:"stop"`
+	MaxTokens        int      `json:"max_tokens"`
+	PresencePenalty  float32  `json:"presence_penalty"`
+	FrequencyPenalty float32  `json:"frequency_penalty"`
+	Truncation       string   `json:"truncation_strategy"`
+	KeepLastTurns    int      `json:"keep_last_turns"`
+	ReserveReply     bool     `json:"reserve_reply_tokens"`
+	Summarize        bool     `json:"summarize"`
+}
+
+type OutputDTO struct {
+	ChatID       string          `json:"chat_id"`
+	UserID       string          `json:"user_id"`
+	Status       string          `json:"status"`
+	TokenUsage   int             `json:"token_usage"`
+	MessageCount int             `json:"message_count"`
+	Config       ConfigOutputDTO `json:"config"`
+}
+
+type UseCase struct {
+	chatGateway gateway.ChatGateway
+}
+
+func NewGetChatUseCase(chatGateway gateway.ChatGateway) *UseCase {
+	return &UseCase{
+		chatGateway: chatGateway,
+	}
+}
+
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	chat, err := this.chatGateway.FindById(ctx, input.ChatID)
+	if err != nil {
+		return nil, errors.New("failed to get chat by id:" + err.Error())
+	}
+	if chat == nil {
+		return nil, entity.ErrChatNotFound
+	}
+	err = chat.CheckOwner(input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &OutputDTO{
+		ChatID:       chat.ID,
+		UserID:       chat.UserID,
+		Status:       chat.Status,
+		TokenUsage:   chat.TokenUsage,
+		MessageCount: len(chat.ErasedMessages) + chat.CountMessages(),
+		Config: ConfigOutputDTO{
+			Model:            chat.Config.Model.GetName(),
+			ModelMaxTokens:   chat.Config.Model.GetMaxTokens(),
+			Temperature:      chat.Config.Temperature,
+			TopP:             chat.Config.TopP,
+			N:                chat.Config.N,
+			Stop:             chat.Config.Stop,
+			MaxTokens:        chat.Config.MaxTokens,
+			PresencePenalty:  chat.Config.PresencePenalty,
+			FrequencyPenalty: chat.Config.FrequencyPenalty,
+			Truncation:       chat.Config.TruncationName(),
+			KeepLastTurns:    chat.Config.TruncationKeepTurns(),
+			ReserveReply:     chat.Config.ReserveReplyTokens,
+			Summarize:        chat.Config.Summarize,
+		},
+	}, nil
+}
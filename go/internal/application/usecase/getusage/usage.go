@@ -0,0 +1,91 @@
+package getusage
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"time"
+)
+
+// defaultPeriod is the window reported when the request gives no start.
+const defaultPeriod = 30 * 24 * time.Hour
+
+var ErrInvalidPeriod = errors.New("invalid usage period")
+
+type InputDTO struct {
+	UserID string    `json:"user_id"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+type ModelUsageOutputDTO struct {
+	Model            string  `json:"model"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+type OutputDTO struct {
+	UserID           string                `json:"user_id"`
+	From             time.Time             `json:"from"`
+	To               time.Time             `json:"to"`
+	Requests         int                   `json:"requests"`
+	PromptTokens     int                   `json:"prompt_tokens"`
+	CompletionTokens int                   `json:"completion_tokens"`
+	Cost             float64               `json:"cost"`
+	Models           []ModelUsageOutputDTO `json:"models"`
+}
+
+type UseCase struct {
+	usageGateway gateway.UsageGateway
+}
+
+func NewGetUsageUseCase(usageGateway gateway.UsageGateway) *UseCase {
+	return &UseCase{
+		usageGateway: usageGateway,
+	}
+}
+
+// Execute aggregates the usage of the user in [From, To). To defaults to now
+// and From to defaultPeriod before To.
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user_id is empty")
+	}
+	to := input.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := input.From
+	if from.IsZero() {
+		from = to.Add(-defaultPeriod)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidPeriod
+	}
+	summaries, err := this.usageGateway.SummarizeByUser(ctx, input.UserID, from, to)
+	if err != nil {
+		return nil, errors.New("failed to summarize usage:" + err.Error())
+	}
+	output := &OutputDTO{
+		UserID: input.UserID,
+		From:   from,
+		To:     to,
+		Models: make([]ModelUsageOutputDTO, 0, len(summaries)),
+	}
+	for _, summary := range summaries {
+		output.Requests += summary.Requests
+		output.PromptTokens += summary.PromptTokens
+		output.CompletionTokens += summary.CompletionTokens
+		output.Cost += summary.Cost
+		output.Models = append(output.Models, ModelUsageOutputDTO{
+			Model:            summary.Model,
+			Requests:         summary.Requests,
+			PromptTokens:     summary.PromptTokens,
+			CompletionTokens: summary.CompletionTokens,
+			Cost:             summary.Cost,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,80 @@
+package listchats
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"time"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type InputDTO struct {
+	UserID string `json:"user_id"`
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+type ChatOutputDTO struct {
+	ChatID     string    `json:"chat_id"`
+	Status     string    `json:"status"`
+	TokenUsage int       `json:"token_usage"`
+	Model      string    `json:"model"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type OutputDTO struct {
+	UserID     string          `json:"user_id"`
+	Chats      []ChatOutputDTO `json:"chats"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+type UseCase struct {
+	chatGateway gateway.ChatGateway
+}
+
+func NewListChatsUseCase(chatGateway gateway.ChatGateway) *UseCase {
+	return &UseCase{
+		chatGateway: chatGateway,
+	}
+}
+
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user_id is empty")
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	chats, nextCursor, err := this.chatGateway.ListByUser(ctx, input.UserID, input.Cursor, limit)
+	if err != nil {
+		if errors.Is(err, gateway.ErrInvalidCursor) {
+			return nil, err
+		}
+		return nil, errors.New("failed to list chats:" + err.Error())
+	}
+	output := &OutputDTO{
+		UserID:     input.UserID,
+		Chats:      make([]ChatOutputDTO, 0, len(chats)),
+		NextCursor: nextCursor,
+	}
+	for _, chat := range chats {
+		output.Chats = append(output.Chats, ChatOutputDTO{
+			ChatID:     chat.ID,
+			Status:     chat.Status,
+			TokenUsage: chat.TokenUsage,
+			Model:      chat.Model,
+			CreatedAt:  chat.CreatedAt,
+			UpdatedAt:  chat.UpdatedAt,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,99 @@
+package listmessages
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"sort"
+	"time"
+)
+
+type InputDTO struct {
+	ChatID          string `json:"chat_id"`
+	UserID          string `json:"user_id"`
+	IncludeArchived bool   `json:"include_archived"`
+}
+
+type MessageOutputDTO struct {
+	ID         string     `json:"id"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Tokens     int        `json:"tokens"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Erased     bool       `json:"erased"`
+	Truncated  bool       `json:"truncated,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+// Archived holds the versions replaced by regenerations and edits, oldest
+// first, when they were asked for.
+type OutputDTO struct {
+	ChatID   string             `json:"chat_id"`
+	Messages []MessageOutputDTO `json:"messages"`
+	Archived []MessageOutputDTO `json:"archived,omitempty"`
+}
+
+type UseCase struct {
+	chatGateway gateway.ChatGateway
+}
+
+func NewListMessagesUseCase(chatGateway gateway.ChatGateway) *UseCase {
+	return &UseCase{
+		chatGateway: chatGateway,
+	}
+}
+
+// Execute returns the whole conversation in chronological order. Erased
+// messages and the window are merged by creation time, as pinned messages (the
+// initial system message, the summary) stay at the head of the window whatever
+// their age.
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	chat, err := this.chatGateway.FindById(ctx, input.ChatID)
+	if err != nil {
+		return nil, errors.New("failed to get chat by id:" + err.Error())
+	}
+	if chat == nil {
+		return nil, entity.ErrChatNotFound
+	}
+	err = chat.CheckOwner(input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]MessageOutputDTO, 0, len(chat.ErasedMessages)+len(chat.Messages))
+	for _, message := range chat.ErasedMessages {
+		messages = append(messages, toOutput(message, true))
+	}
+	for _, message := range chat.Messages {
+		messages = append(messages, toOutput(message, false))
+	}
+	// stable, so messages created at the same instant keep their stored order
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	var archived []MessageOutputDTO
+	if input.IncludeArchived {
+		archived = make([]MessageOutputDTO, 0, len(chat.ArchivedMessages))
+		for _, message := range chat.ArchivedMessages {
+			archived = append(archived, toOutput(message, false))
+		}
+	}
+	return &OutputDTO{
+		ChatID:   chat.ID,
+		Messages: messages,
+		Archived: archived,
+	}, nil
+}
+
+func toOutput(message *entity.Message, erased bool) MessageOutputDTO {
+	return MessageOutputDTO{
+		ID:         message.ID,
+		Role:       message.Role,
+		Content:    message.Content,
+		Tokens:     message.Tokens,
+		CreatedAt:  message.CreatedAt,
+		Erased:     erased,
+		Truncated:  message.Truncated,
+		ArchivedAt: message.ArchivedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package regenerateanswer
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+)
+
+type InputDTO struct {
+	ChatID            string `json:"chat_id"`
+	UserID            string `json:"user_id"`
+	CumulativeContent bool   `json:"cumulative_content"`
+}
+
+type UseCase struct {
+	completionStreamUseCase *chatcompletionstream.UseCase
+}
+
+func NewRegenerateAnswerUseCase(completionStreamUseCase *chatcompletionstream.UseCase) *UseCase {
+	return &UseCase{
+		completionStreamUseCase: completionStreamUseCase,
+	}
+}
+
+// Execute archives the last answer of the chat and streams a new one, as
+// chatcompletionstream.UseCase.Execute does.
+func (this *UseCase) Execute(
+	input InputDTO,
+	stream chan<- chatcompletionstream.OutputDTO,
+	ctx context.Context,
+) (*chatcompletionstream.OutputDTO, error) {
+	completionInput := &chatcompletionstream.InputDTO{
+		ChatID:            input.ChatID,
+		UserID:            input.UserID,
+		CumulativeContent: input.CumulativeContent,
+	}
+	conversation, err := this.completionStreamUseCase.OpenExisting(completionInput, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conversation.Regenerate(completionInput, stream, ctx)
+}
@@ -0,0 +1,50 @@
+package revokeapikey
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"time"
+)
+
+type InputDTO struct {
+	ID string `json:"id"`
+}
+
+type OutputDTO struct {
+	ID        string    `json:"id"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type UseCase struct {
+	apiKeyGateway gateway.APIKeyGateway
+}
+
+func NewRevokeAPIKeyUseCase(apiKeyGateway gateway.APIKeyGateway) *UseCase {
+	return &UseCase{
+		apiKeyGateway: apiKeyGateway,
+	}
+}
+
+// Execute revokes the key; revoking a revoked key keeps its first revocation.
+func (this *UseCase) Execute(input InputDTO, ctx context.Context) (*OutputDTO, error) {
+	key, err := this.apiKeyGateway.FindById(ctx, input.ID)
+	if err != nil {
+		return nil, errors.New("failed to get api key by id:" + err.Error())
+	}
+	if key == nil {
+		return nil, entity.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.Revoke()
+		err = this.apiKeyGateway.Revoke(ctx, key)
+		if err != nil {
+			return nil, errors.New("failed to revoke api key:" + err.Error())
+		}
+	}
+	return &OutputDTO{
+		ID:        key.ID,
+		RevokedAt: *key.RevokedAt,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeChatRead  = "chat:read"
+	ScopeChatWrite = "chat:write"
+	// ScopeAdmin grants every other scope.
+	ScopeAdmin = "admin"
+
+	apiKeyPrefix = "csk_"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey is every authentication failure of a key: unknown,
+	// malformed, wrong secret, expired or revoked.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrInvalidAPIKeySpec wraps every rejection of the settings of a new key.
+	ErrInvalidAPIKeySpec = errors.New("invalid api key settings")
+)
+
+// APIKey is a long-lived credential of OwnerID. Only the SHA-256 hash of its
+// secret is kept; the plain key is shown once, when the key is created.
+type APIKey struct {
+	ID         string
+	Name       string
+	OwnerID    string
+	Hash       string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// NewAPIKey returns the key and its plain value, "csk_<id>_<secret>".
+func NewAPIKey(name string, ownerID string, scopes []string, expiresAt *time.Time) (*APIKey, string, error) {
+	key := &APIKey{
+		ID:        uuid.NewString(),
+		Name:      name,
+		OwnerID:   ownerID,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	err := key.validate()
+	if err != nil {
+		return nil, "", err
+	}
+	random := make([]byte, 32)
+	_, err = rand.Read(random)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(random)
+	key.Hash = hashSecret(secret)
+	return key, apiKeyPrefix + key.ID + "_" + secret, nil
+}
+
+// ParseAPIKey splits a plain key into its id and secret.
+func ParseAPIKey(plain string) (string, string, error) {
+	rest, found := strings.CutPrefix(plain, apiKeyPrefix)
+	if !found {
+		return "", "", ErrInvalidAPIKey
+	}
+	id, secret, found := strings.Cut(rest, "_")
+	if !found || id == "" || secret == "" {
+		return "", "", ErrInvalidAPIKey
+	}
+	return id, secret, nil
+}
+
+// IsAPIKey tells plain API keys apart from other bearer credentials.
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+func (this *APIKey) validate() error {
+	if strings.TrimSpace(this.Name) == "" {
+		return fmt.Errorf("%w: name is empty", ErrInvalidAPIKeySpec)
+	}
+	if this.OwnerID == "" {
+		return fmt.Errorf("%w: owner_id is empty", ErrInvalidAPIKeySpec)
+	}
+	if len(this.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeySpec)
+	}
+	for _, scope := range this.Scopes {
+		if scope != ScopeChatRead && scope != ScopeChatWrite && scope != ScopeAdmin {
+			return fmt.Errorf("%w: unknown scope %s", ErrInvalidAPIKeySpec, scope)
+		}
+	}
+	if this.ExpiresAt != nil && !this.ExpiresAt.After(this.CreatedAt) {
+		return fmt.Errorf("%w: expires_at is in the past", ErrInvalidAPIKeySpec)
+	}
+	return nil
+}
+
+// Verify compares the secret with the stored hash in constant time.
+func (this *APIKey) Verify(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(this.Hash)) == 1
+}
+
+func (this *APIKey) IsActive(now time.Time) bool {
+	if this.RevokedAt != nil {
+		return false
+	}
+	return this.ExpiresAt == nil || now.Before(*this.ExpiresAt)
+}
+
+func (this *APIKey) Revoke() {
+	now := time.Now()
+	this.RevokedAt = &now
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasScope tells whether scopes grant scope; admin grants everything.
+func HasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,25 @@ package entity
 
 import (
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"time"
 )
 
+var (
+	ErrChatNotFound  = errors.New("chat not found")
+	ErrChatClosed    = errors.New("chat is closed, no more messages allowed")
+	ErrChatForbidden = errors.New("chat belongs to another user")
+	// ErrNothingToRegenerate is returned when the chat does not end with an
+	// answer to a user message.
+	ErrNothingToRegenerate = errors.New("chat has no answer to regenerate")
+	ErrMessageNotEditable  = errors.New("only the last user message of the chat can be edited")
+	// ErrInvalidChatConfig wraps every rejection of a client supplied setting.
+	ErrInvalidChatConfig = errors.New("invalid chat config")
+)
+
+const maxStopSequences = 4
+
 type ChatConfig struct {
 	Model            *Model
 	Temperature      float32
@@ -14,6 +30,13 @@ type ChatConfig struct {
 	MaxTokens        int
 	PresencePenalty  float32
 	FrequencyPenalty float32
+	// Truncation picks the messages evicted when the window is full; nil keeps
+	// the historical behavior of dropping the oldest message, system prompt included.
+	Truncation TruncationStrategy
+	// ReserveReplyTokens keeps MaxTokens free for the answer when building a prompt.
+	ReserveReplyTokens bool
+	// Summarize condenses erased messages into a pinned summary system message.
+	Summarize bool
 }
 
 type Chat struct {
@@ -22,9 +45,19 @@ type Chat struct {
 	InitialSystemMessage *Message
 	Messages             []*Message
 	ErasedMessages       []*Message
-	Status               string
-	TokenUsage           int
-	Config               *ChatConfig
+	// ArchivedMessages are the versions replaced by a regeneration or an edit,
+	// oldest first. They are never sent to the model.
+	ArchivedMessages []*Message
+	Status           string
+	TokenUsage       int
+	Config           *ChatConfig
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	// Version is the optimistic lock of the chat, bumped on every save.
+	Version int
+	// Summary is the pinned system message condensing SummarySourceIDs.
+	Summary          *Message
+	SummarySourceIDs []string
 }
 
 func NewChat(userID string, initialSystemMessage *Message, config *ChatConfig) (*Chat, error) {
@@ -35,9 +68,14 @@ func NewChat(userID string, initialSystemMessage *Message, config *ChatConfig) (
 		Status:               "active",
 		Config:               config,
 		TokenUsage:           0,
+		CreatedAt:            time.Now(),
+		UpdatedAt:            time.Now(),
 	}
-	err := chat.AddMessage(initialSystemMessage)
-	err = chat.validate()
+	err := chat.validate()
+	if err != nil {
+		return nil, err
+	}
+	err = chat.AddMessage(initialSystemMessage)
 	if err != nil {
 		return nil, err
 	}
@@ -54,11 +92,35 @@ func (this *Chat) validate() error {
 	if this.Status != "active" && this.Status != "closed" {
 		return errors.New("invalid status")
 	}
+	if this.Config.Model == nil {
+		return fmt.Errorf("%w: model is empty", ErrInvalidChatConfig)
+	}
 	if this.Config.Temperature < 0.0 || this.Config.Temperature > 2.0 {
-		return errors.New("invalid temperature")
+		return fmt.Errorf("%w: invalid temperature", ErrInvalidChatConfig)
 	}
 	if this.Config.TopP < 0.0 || this.Config.TopP > 1.0 {
-		return errors.New("invalid top_p")
+		return fmt.Errorf("%w: invalid top_p", ErrInvalidChatConfig)
+	}
+	if this.Config.MaxTokens < 1 || this.Config.MaxTokens > this.Config.Model.GetMaxTokens() {
+		return fmt.Errorf("%w: invalid max_tokens", ErrInvalidChatConfig)
+	}
+	if this.Config.Summarize && this.Config.TruncationName() == TruncationDropOldest {
+		return fmt.Errorf("%w: summarization needs a truncation strategy that pins system messages", ErrInvalidChatConfig)
+	}
+	if maxOutput := this.Config.Model.GetMaxOutputTokens(); maxOutput > 0 && this.Config.MaxTokens > maxOutput {
+		return fmt.Errorf("%w: max_tokens exceeds the model output limit of %d", ErrInvalidChatConfig, maxOutput)
+	}
+	if this.Config.ReserveReplyTokens && this.Config.MaxTokens >= this.Config.Model.GetMaxTokens() {
+		return fmt.Errorf("%w: max_tokens leaves no room for the prompt", ErrInvalidChatConfig)
+	}
+	if len(this.Config.Stop) > maxStopSequences {
+		return fmt.Errorf("%w: at most %d stop sequences are allowed", ErrInvalidChatConfig, maxStopSequences)
+	}
+	if this.Config.PresencePenalty < -2.0 || this.Config.PresencePenalty > 2.0 {
+		return fmt.Errorf("%w: invalid presence_penalty", ErrInvalidChatConfig)
+	}
+	if this.Config.FrequencyPenalty < -2.0 || this.Config.FrequencyPenalty > 2.0 {
+		return fmt.Errorf("%w: invalid frequency_penalty", ErrInvalidChatConfig)
 	}
 
 	return nil
@@ -66,23 +128,38 @@ func (this *Chat) validate() error {
 
 func (this *Chat) AddMessage(message *Message) error {
 	if this.Status == "closed" {
-		return errors.New("chat is closed, no more messages allowed")
+		return ErrChatClosed
 	}
-	for {
-		if this.Config.Model.GetMaxTokens() >= message.GetCountTokens()+this.TokenUsage {
-			// not full flow
-			this.Messages = append(this.Messages, message)
-			this.refreshTokenUsage()
-			break
-		}
-		// full flow (remove the oldest message while not enough space)
-		this.ErasedMessages = append(this.ErasedMessages, this.Messages[0])
-		this.Messages = this.Messages[1:]
-		this.refreshTokenUsage()
+	messages := make([]*Message, 0, len(this.Messages)+1)
+	messages = append(messages, this.Messages...)
+	messages = append(messages, message)
+	kept, evicted, err := this.truncation().Truncate(messages, this.tokenBudget(message))
+	if err != nil {
+		return err
 	}
+	this.Messages = kept
+	this.ErasedMessages = append(this.ErasedMessages, evicted...)
+	this.refreshTokenUsage()
 	return nil
 }
 
+func (this *Chat) truncation() TruncationStrategy {
+	if this.Config.Truncation == nil {
+		return &DropOldest{}
+	}
+	return this.Config.Truncation
+}
+
+// tokenBudget is the room the window may take once message is added. The reply
+// reservation only applies to prompts, so an assistant answer never evicts for it.
+func (this *Chat) tokenBudget(message *Message) int {
+	budget := this.Config.Model.GetMaxTokens() - replyPrimingTokens
+	if this.Config.ReserveReplyTokens && message.Role != "assistant" {
+		budget -= this.Config.MaxTokens
+	}
+	return budget
+}
+
 func (this *Chat) GetMessages() []*Message {
 	return this.Messages
 }
@@ -91,13 +168,94 @@ func (this *Chat) CountMessages() int {
 	return len(this.Messages)
 }
 
+// CheckOwner fails with ErrChatForbidden unless the chat belongs to userID.
+func (this *Chat) CheckOwner(userID string) error {
+	if this.UserID != userID {
+		return ErrChatForbidden
+	}
+	return nil
+}
+
 func (this *Chat) Close() {
 	this.Status = "closed"
 }
 
+func (this *Chat) Reopen() {
+	this.Status = "active"
+}
+
 func (this *Chat) refreshTokenUsage() {
 	this.TokenUsage = 0
 	for _, message := range this.Messages {
 		this.TokenUsage += message.GetCountTokens()
 	}
+	if len(this.Messages) > 0 {
+		this.TokenUsage += replyPrimingTokens
+	}
+}
+
+// DropLastAnswer archives the assistant message closing the window, so the
+// user message before it can be answered again. A window that already ends with
+// a user message, whose generation was cancelled before any text, is kept as is
+// and no answer is returned.
+func (this *Chat) DropLastAnswer() (*Message, error) {
+	if this.Status == "closed" {
+		return nil, ErrChatClosed
+	}
+	last := len(this.Messages) - 1
+	if last >= 0 && this.Messages[last].Role == "user" {
+		return nil, nil
+	}
+	if last < 1 || this.Messages[last].Role != "assistant" || this.Messages[last-1].Role != "user" {
+		return nil, ErrNothingToRegenerate
+	}
+	answer := this.Messages[last]
+	this.Messages = this.Messages[:last]
+	this.archive(answer)
+	this.refreshTokenUsage()
+	return answer, nil
+}
+
+// EditLastUserMessage replaces the last user message of the window, which must
+// be messageID, by message. The replaced message and the answers after it are
+// archived.
+func (this *Chat) EditLastUserMessage(messageID string, message *Message) error {
+	if this.Status == "closed" {
+		return ErrChatClosed
+	}
+	if message.Role != "user" {
+		return errors.New("an edit must be a user message")
+	}
+	last := -1
+	for i := len(this.Messages) - 1; i >= 0; i-- {
+		if this.Messages[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 || this.Messages[last].ID != messageID {
+		return ErrMessageNotEditable
+	}
+	// the window without the replaced turn, to restore if message does not fit
+	messages := this.Messages
+	erased := this.ErasedMessages
+	this.Messages = this.Messages[:last]
+	err := this.AddMessage(message)
+	if err != nil {
+		this.Messages = messages
+		this.ErasedMessages = erased
+		this.refreshTokenUsage()
+		return err
+	}
+	this.archive(messages[last:]...)
+	return nil
+}
+
+// archive keeps messages taken out of the window as replaced versions.
+func (this *Chat) archive(replaced ...*Message) {
+	now := time.Now()
+	for _, message := range replaced {
+		message.ArchivedAt = &now
+	}
+	this.ArchivedMessages = append(this.ArchivedMessages, replaced...)
 }
@@ -3,28 +3,42 @@ package entity
 import (
 	"errors"
 	"github.com/google/uuid"
-	"github.com/pkoukk/tiktoken-go"
 	"time"
 )
 
 type Message struct {
-	ID        string
-	Role      string
-	Content   string
+	ID      string
+	Role    string
+	Content string
+	// Tokens is what the message takes in a prompt, chat-format overhead included.
 	Tokens    int
 	Model     *Model
 	CreatedAt time.Time
+	// PromptTokens and CompletionTokens are the usage of the turn an assistant
+	// message answers; they are zero on other messages.
+	PromptTokens     int
+	CompletionTokens int
+	// Truncated marks an answer whose generation was cancelled before the end.
+	Truncated bool
+	// ArchivedAt is set once a regeneration or an edit replaced the message.
+	ArchivedAt *time.Time
+
+	formatTokens int
 }
 
 func NewMessage(role string, content string, model *Model) (*Message, error) {
-	tokens, err := countTokens(content, model)
+	contentTokens, formatTokens, err := countTokens(role, content, model)
+	if err != nil {
+		return nil, errors.New("failed to count tokens:" + err.Error())
+	}
 	msg := &Message{
-		ID:        uuid.NewString(),
-		Role:      role,
-		Content:   content,
-		Tokens:    tokens,
-		Model:     model,
-		CreatedAt: time.Now(),
+		ID:           uuid.NewString(),
+		Role:         role,
+		Content:      content,
+		Tokens:       contentTokens + formatTokens,
+		Model:        model,
+		CreatedAt:    time.Now(),
+		formatTokens: formatTokens,
 	}
 	err = msg.validate()
 	if err != nil {
@@ -46,12 +60,27 @@ func (this *Message) validate() error {
 	return nil
 }
 
-func countTokens(content string, model *Model) (int, error) {
-	tkm, err := tiktoken.EncodingForModel(model.GetName())
+func countTokens(role string, content string, model *Model) (int, int, error) {
+	encoder, err := encoderFor(model)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
-	return len(tkm.Encode(content, nil, nil)), nil
+	return len(encoder.Encode(content, nil, nil)), messageFormatTokens(role, model, encoder), nil
+}
+
+// SetUsage records the usage the provider reported for the turn. The reported
+// completion is exact, so it replaces the local count of the content.
+func (this *Message) SetUsage(promptTokens int, completionTokens int) {
+	this.PromptTokens = promptTokens
+	this.CompletionTokens = completionTokens
+	this.Tokens = completionTokens + this.formatTokens
+}
+
+// EstimateUsage records the local counts as the usage of the turn, for
+// providers that do not report it.
+func (this *Message) EstimateUsage(promptTokens int) {
+	this.PromptTokens = promptTokens
+	this.CompletionTokens = this.Tokens - this.formatTokens
 }
 
 func (this *Message) GetCountTokens() int {
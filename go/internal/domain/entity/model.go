@@ -1,10 +1,24 @@
 package entity
 
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownModel is an ErrInvalidChatConfig: chats can only use registered models.
+var ErrUnknownModel = fmt.Errorf("%w: unknown model", ErrInvalidChatConfig)
+
 type Model struct {
-	name      string
-	maxTokens int
+	name            string
+	maxTokens       int
+	maxOutputTokens int
+	encoding        string
+	promptPrice     float64
+	completionPrice float64
 }
 
+// NewModel describes a model that is not in the registry; its tokenizer is
+// guessed from the name and it has no output limit nor price.
 func NewModel(name string, maxTokens int) *Model {
 	return &Model{
 		name:      name,
@@ -12,10 +26,93 @@ func NewModel(name string, maxTokens int) *Model {
 	}
 }
 
+// ModelSpec is one entry of the model registry file. Prices are per 1K tokens.
+type ModelSpec struct {
+	Name            string
+	ContextWindow   int
+	MaxOutputTokens int
+	Encoding        string
+	PromptPrice     float64
+	CompletionPrice float64
+}
+
 func (this *Model) GetName() string {
 	return this.name
 }
 
+// GetMaxTokens is the context window of the model.
 func (this *Model) GetMaxTokens() int {
 	return this.maxTokens
 }
+
+// GetMaxOutputTokens is the largest answer the model can produce, 0 if unknown.
+func (this *Model) GetMaxOutputTokens() int {
+	return this.maxOutputTokens
+}
+
+func (this *Model) GetEncoding() string {
+	return this.encoding
+}
+
+func (this *Model) GetPromptPrice() float64 {
+	return this.promptPrice
+}
+
+func (this *Model) GetCompletionPrice() float64 {
+	return this.completionPrice
+}
+
+// maxModelNameLength is the longest model name chats and usage events can store.
+const maxModelNameLength = 50
+
+type ModelRegistry struct {
+	models map[string]*Model
+}
+
+func NewModelRegistry(specs []ModelSpec) (*ModelRegistry, error) {
+	registry := &ModelRegistry{
+		models: make(map[string]*Model, len(specs)),
+	}
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return nil, errors.New("model name is empty")
+		}
+		if len(spec.Name) > maxModelNameLength {
+			return nil, fmt.Errorf("model %s: name longer than %d characters", spec.Name, maxModelNameLength)
+		}
+		if spec.ContextWindow <= 0 {
+			return nil, fmt.Errorf("model %s: invalid context window", spec.Name)
+		}
+		if spec.MaxOutputTokens < 0 || spec.MaxOutputTokens > spec.ContextWindow {
+			return nil, fmt.Errorf("model %s: invalid max output tokens", spec.Name)
+		}
+		if spec.Encoding == "" {
+			return nil, fmt.Errorf("model %s: encoding is empty", spec.Name)
+		}
+		if _, exists := registry.models[spec.Name]; exists {
+			return nil, fmt.Errorf("model %s is registered twice", spec.Name)
+		}
+		registry.models[spec.Name] = &Model{
+			name:            spec.Name,
+			maxTokens:       spec.ContextWindow,
+			maxOutputTokens: spec.MaxOutputTokens,
+			encoding:        spec.Encoding,
+			promptPrice:     spec.PromptPrice,
+			completionPrice: spec.CompletionPrice,
+		}
+	}
+	return registry, nil
+}
+
+func (this *ModelRegistry) Get(name string) (*Model, error) {
+	model, ok := this.models[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownModel, name)
+	}
+	return model, nil
+}
+
+// Cost prices a completion with the per-1K-token prices of the model.
+func (this *Model) Cost(promptTokens int, completionTokens int) float64 {
+	return float64(promptTokens)/1000*this.promptPrice + float64(completionTokens)/1000*this.completionPrice
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	QuotaRequestsPerMinute = "requests_per_minute"
+	QuotaTokensPerDay      = "tokens_per_day"
+)
+
+// QuotaTier holds the limits of a group of users; a zero limit is unlimited.
+type QuotaTier struct {
+	Name              string
+	RequestsPerMinute int
+	TokensPerDay      int
+}
+
+// QuotaExceededError is returned when a user reached one of the limits of its
+// tier; RetryAfter is the time left until the limit resets.
+type QuotaExceededError struct {
+	UserID     string
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (this *QuotaExceededError) Error() string {
+	return fmt.Sprintf("user %s exceeded its %s quota, retry in %s", this.UserID, this.Limit, this.RetryAfter.Round(time.Second))
+}
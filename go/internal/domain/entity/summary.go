@@ -0,0 +1,52 @@
+package entity
+
+// SummaryPendingMessages returns the erased user/assistant messages that are
+// not covered by the current summary yet, oldest first.
+func (this *Chat) SummaryPendingMessages() []*Message {
+	summarized := make(map[string]bool, len(this.SummarySourceIDs))
+	for _, id := range this.SummarySourceIDs {
+		summarized[id] = true
+	}
+	var pending []*Message
+	for _, message := range this.ErasedMessages {
+		if message.Role == "system" || summarized[message.ID] {
+			continue
+		}
+		pending = append(pending, message)
+	}
+	return pending
+}
+
+// ApplySummary replaces the pinned summary message by summary, which now also
+// covers sources. The summary sits right after the leading system messages; if
+// it makes the window overflow, the truncation strategy evicts as usual.
+func (this *Chat) ApplySummary(summary *Message, sources []*Message) error {
+	messages := make([]*Message, 0, len(this.Messages)+1)
+	inserted := false
+	for _, message := range this.Messages {
+		if this.Summary != nil && message.ID == this.Summary.ID {
+			continue
+		}
+		if !inserted && message.Role != "system" {
+			messages = append(messages, summary)
+			inserted = true
+		}
+		messages = append(messages, message)
+	}
+	if !inserted {
+		messages = append(messages, summary)
+	}
+	last := messages[len(messages)-1]
+	kept, evicted, err := this.truncation().Truncate(messages, this.tokenBudget(last))
+	if err != nil {
+		return err
+	}
+	this.Messages = kept
+	this.ErasedMessages = append(this.ErasedMessages, evicted...)
+	this.Summary = summary
+	for _, source := range sources {
+		this.SummarySourceIDs = append(this.SummarySourceIDs, source.ID)
+	}
+	this.refreshTokenUsage()
+	return nil
+}
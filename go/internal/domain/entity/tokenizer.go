@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"fmt"
+	"github.com/pkoukk/tiktoken-go"
+	"strings"
+	"sync"
+)
+
+// replyPrimingTokens is the overhead of every prompt: the chat format primes
+// the answer with an assistant header.
+const replyPrimingTokens = 3
+
+// encoders caches one tokenizer per encoding name; building one is expensive.
+var encoders sync.Map
+
+func encoderFor(model *Model) (*tiktoken.Tiktoken, error) {
+	encoding := model.GetEncoding()
+	if encoding == "" {
+		encoding = encodingForModelName(model.GetName())
+	}
+	if encoding == "" {
+		return nil, fmt.Errorf("no encoding for model %s", model.GetName())
+	}
+	if encoder, ok := encoders.Load(encoding); ok {
+		return encoder.(*tiktoken.Tiktoken), nil
+	}
+	encoder, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := encoders.LoadOrStore(encoding, encoder)
+	return actual.(*tiktoken.Tiktoken), nil
+}
+
+func encodingForModelName(name string) string {
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[name]; ok {
+		return encoding
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(name, prefix) {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// messageFormatTokens is what the chat format adds to a message besides its
+// content: the per-message separators and the encoded role.
+func messageFormatTokens(role string, model *Model, encoder *tiktoken.Tiktoken) int {
+	tokensPerMessage := 3
+	// the first gpt-3.5-turbo snapshot used a more verbose message header
+	if strings.HasPrefix(model.GetName(), "gpt-3.5-turbo-0301") {
+		tokensPerMessage = 4
+	}
+	return tokensPerMessage + len(encoder.Encode(role, nil, nil))
+}
+
+// CountTokens counts the tokens of content alone, without the chat-format
+// overhead of a message.
+func CountTokens(content string, model *Model) (int, error) {
+	encoder, err := encoderFor(model)
+	if err != nil {
+		return 0, err
+	}
+	return len(encoder.Encode(content, nil, nil)), nil
+}
+
+// PromptTokens is what messages take once sent as a prompt.
+func PromptTokens(messages []*Message) int {
+	tokens := replyPrimingTokens
+	for _, message := range messages {
+		tokens += message.Tokens
+	}
+	return tokens
+}
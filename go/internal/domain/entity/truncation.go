@@ -0,0 +1,161 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrMessageTooLarge = errors.New("message does not fit in the model context window")
+
+const (
+	TruncationDropOldest      = "drop_oldest"
+	TruncationPinSystem       = "pin_system"
+	TruncationDropOldestPairs = "drop_oldest_pairs"
+	TruncationKeepLastTurns   = "keep_last_turns"
+)
+
+// TruncationStrategy decides which messages leave the context window when a
+// new message does not fit. messages always ends with the message being added,
+// which must be kept; evicted messages are returned in their original order.
+type TruncationStrategy interface {
+	Name() string
+	Truncate(messages []*Message, budget int) (kept []*Message, evicted []*Message, err error)
+}
+
+func NewTruncationStrategy(name string, keepTurns int) (TruncationStrategy, error) {
+	switch name {
+	case "", TruncationDropOldest:
+		return &DropOldest{}, nil
+	case TruncationPinSystem:
+		return &DropOldest{PinSystem: true}, nil
+	case TruncationDropOldestPairs:
+		return &DropOldestPairs{}, nil
+	case TruncationKeepLastTurns:
+		if keepTurns < 1 {
+			return nil, fmt.Errorf("%w: keep_last_turns must be at least 1", ErrInvalidChatConfig)
+		}
+		return &KeepLastTurns{Turns: keepTurns}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown truncation strategy %s", ErrInvalidChatConfig, name)
+}
+
+// TruncationName is the persisted name of the strategy of the config.
+func (this *ChatConfig) TruncationName() string {
+	if this.Truncation == nil {
+		return TruncationDropOldest
+	}
+	return this.Truncation.Name()
+}
+
+// TruncationKeepTurns is the turn limit of a KeepLastTurns strategy, 0 otherwise.
+func (this *ChatConfig) TruncationKeepTurns() int {
+	if strategy, ok := this.Truncation.(*KeepLastTurns); ok {
+		return strategy.Turns
+	}
+	return 0
+}
+
+// DropOldest evicts one message at a time, oldest first. Without PinSystem the
+// system prompt is evicted like any other message.
+type DropOldest struct {
+	PinSystem bool
+}
+
+func (this *DropOldest) Name() string {
+	if this.PinSystem {
+		return TruncationPinSystem
+	}
+	return TruncationDropOldest
+}
+
+func (this *DropOldest) Truncate(messages []*Message, budget int) ([]*Message, []*Message, error) {
+	return truncate(messages, budget, this.PinSystem, false, 0)
+}
+
+// DropOldestPairs keeps system messages and evicts whole turns (a user message
+// and the assistant answers that follow it), oldest first.
+type DropOldestPairs struct{}
+
+func (this *DropOldestPairs) Name() string {
+	return TruncationDropOldestPairs
+}
+
+func (this *DropOldestPairs) Truncate(messages []*Message, budget int) ([]*Message, []*Message, error) {
+	return truncate(messages, budget, true, true, 0)
+}
+
+// KeepLastTurns behaves like DropOldestPairs but never keeps more than Turns
+// turns, the one being added included.
+type KeepLastTurns struct {
+	Turns int
+}
+
+func (this *KeepLastTurns) Name() string {
+	return TruncationKeepLastTurns
+}
+
+func (this *KeepLastTurns) Truncate(messages []*Message, budget int) ([]*Message, []*Message, error) {
+	return truncate(messages, budget, true, true, this.Turns)
+}
+
+func truncate(messages []*Message, budget int, pinSystem bool, byTurn bool, maxTurns int) ([]*Message, []*Message, error) {
+	if len(messages) == 0 {
+		return messages, nil, nil
+	}
+	last := messages[len(messages)-1]
+	// pinned system messages belong to no unit, so they are never evicted
+	var units [][]*Message
+	for _, message := range messages {
+		if pinSystem && message.Role == "system" && message != last {
+			continue
+		}
+		if len(units) == 0 || !byTurn || message.Role == "user" {
+			units = append(units, []*Message{message})
+			continue
+		}
+		units[len(units)-1] = append(units[len(units)-1], message)
+	}
+
+	evicted := make(map[*Message]bool)
+	used := 0
+	for _, message := range messages {
+		used += message.GetCountTokens()
+	}
+	evictUnit := func() {
+		for _, message := range units[0] {
+			evicted[message] = true
+			used -= message.GetCountTokens()
+		}
+		units = units[1:]
+	}
+	if maxTurns > 0 {
+		for len(units) > maxTurns {
+			evictUnit()
+		}
+	}
+	for used > budget {
+		if len(units) > 1 {
+			evictUnit()
+			continue
+		}
+		// only the unit holding the new message is left: drop what precedes it
+		current := units[0]
+		if len(current) == 1 {
+			return nil, nil, fmt.Errorf("%w: %d tokens needed, %d available", ErrMessageTooLarge, used, budget)
+		}
+		evicted[current[0]] = true
+		used -= current[0].GetCountTokens()
+		units[0] = current[1:]
+	}
+
+	var kept []*Message
+	var dropped []*Message
+	for _, message := range messages {
+		if evicted[message] {
+			dropped = append(dropped, message)
+			continue
+		}
+		kept = append(kept, message)
+	}
+	return kept, dropped, nil
+}
@@ -0,0 +1,218 @@
+package entity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testMessage(id string, role string, tokens int) *Message {
+	return &Message{ID: id, Role: role, Tokens: tokens}
+}
+
+func messageIDs(messages []*Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    string
+		keepTurns   int
+		messages    []*Message
+		budget      int
+		wantKept    []string
+		wantEvicted []string
+		wantErr     error
+	}{
+		{
+			name:     "drop_oldest keeps everything under budget",
+			strategy: TruncationDropOldest,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+			},
+			budget:      20,
+			wantKept:    []string{"s", "u1"},
+			wantEvicted: []string{},
+		},
+		{
+			name:     "drop_oldest evicts the system prompt first",
+			strategy: TruncationDropOldest,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 10),
+				testMessage("u2", "user", 10),
+			},
+			budget:      30,
+			wantKept:    []string{"u1", "a1", "u2"},
+			wantEvicted: []string{"s"},
+		},
+		{
+			name:     "drop_oldest fails when the new message alone is over budget",
+			strategy: TruncationDropOldest,
+			messages: []*Message{
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 10),
+				testMessage("u2", "user", 50),
+			},
+			budget:  40,
+			wantErr: ErrMessageTooLarge,
+		},
+		{
+			name:     "pin_system evicts the oldest unpinned message",
+			strategy: TruncationPinSystem,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 10),
+				testMessage("u2", "user", 10),
+			},
+			budget:      30,
+			wantKept:    []string{"s", "a1", "u2"},
+			wantEvicted: []string{"u1"},
+		},
+		{
+			name:     "pin_system never evicts system messages",
+			strategy: TruncationPinSystem,
+			messages: []*Message{
+				testMessage("s", "system", 30),
+				testMessage("u1", "user", 10),
+				testMessage("u2", "user", 20),
+			},
+			budget:  40,
+			wantErr: ErrMessageTooLarge,
+		},
+		{
+			name:     "drop_oldest_pairs evicts whole turns",
+			strategy: TruncationDropOldestPairs,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 10),
+				testMessage("u2", "user", 10),
+				testMessage("a2", "assistant", 10),
+				testMessage("u3", "user", 10),
+			},
+			budget:      45,
+			wantKept:    []string{"s", "u2", "a2", "u3"},
+			wantEvicted: []string{"u1", "a1"},
+		},
+		{
+			name:     "drop_oldest_pairs drops the head of the current turn last",
+			strategy: TruncationDropOldestPairs,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 50),
+			},
+			budget:      65,
+			wantKept:    []string{"s", "a1"},
+			wantEvicted: []string{"u1"},
+		},
+		{
+			name:      "keep_last_turns bounds the turns under budget",
+			strategy:  TruncationKeepLastTurns,
+			keepTurns: 2,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 10),
+				testMessage("u2", "user", 10),
+				testMessage("a2", "assistant", 10),
+				testMessage("u3", "user", 10),
+			},
+			budget:      1000,
+			wantKept:    []string{"s", "u2", "a2", "u3"},
+			wantEvicted: []string{"u1", "a1"},
+		},
+		{
+			name:      "keep_last_turns still enforces the budget",
+			strategy:  TruncationKeepLastTurns,
+			keepTurns: 3,
+			messages: []*Message{
+				testMessage("s", "system", 10),
+				testMessage("u1", "user", 10),
+				testMessage("a1", "assistant", 10),
+				testMessage("u2", "user", 10),
+			},
+			budget:      20,
+			wantKept:    []string{"s", "u2"},
+			wantEvicted: []string{"u1", "a1"},
+		},
+		{
+			name:      "keep_last_turns fails when the pinned prompt leaves no room",
+			strategy:  TruncationKeepLastTurns,
+			keepTurns: 3,
+			messages: []*Message{
+				testMessage("s", "system", 30),
+				testMessage("u1", "user", 20),
+			},
+			budget:  40,
+			wantErr: ErrMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := NewTruncationStrategy(tt.strategy, tt.keepTurns)
+			if err != nil {
+				t.Fatalf("NewTruncationStrategy: %v", err)
+			}
+			kept, evicted, err := strategy.Truncate(tt.messages, tt.budget)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Truncate: %v", err)
+			}
+			if got := messageIDs(kept); !reflect.DeepEqual(got, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", got, tt.wantKept)
+			}
+			if got := messageIDs(evicted); !reflect.DeepEqual(got, tt.wantEvicted) {
+				t.Errorf("evicted = %v, want %v", got, tt.wantEvicted)
+			}
+		})
+	}
+}
+
+func TestNewTruncationStrategy(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  string
+		keepTurns int
+		wantName  string
+		wantErr   error
+	}{
+		{name: "empty defaults to drop_oldest", strategy: "", wantName: TruncationDropOldest},
+		{name: "pin_system", strategy: TruncationPinSystem, wantName: TruncationPinSystem},
+		{name: "drop_oldest_pairs", strategy: TruncationDropOldestPairs, wantName: TruncationDropOldestPairs},
+		{name: "keep_last_turns", strategy: TruncationKeepLastTurns, keepTurns: 1, wantName: TruncationKeepLastTurns},
+		{name: "keep_last_turns without turns", strategy: TruncationKeepLastTurns, wantErr: ErrInvalidChatConfig},
+		{name: "unknown", strategy: "drop_newest", wantErr: ErrInvalidChatConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := NewTruncationStrategy(tt.strategy, tt.keepTurns)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTruncationStrategy: %v", err)
+			}
+			if strategy.Name() != tt.wantName {
+				t.Errorf("Name() = %s, want %s", strategy.Name(), tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// UsageEvent is one billed completion. Its cost is frozen when the event is
+// created, so later price changes do not rewrite past spend.
+type UsageEvent struct {
+	ID               string
+	UserID           string
+	ChatID           string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	CreatedAt        time.Time
+}
+
+func NewUsageEvent(userID string, chatID string, model *Model, promptTokens int, completionTokens int) *UsageEvent {
+	return &UsageEvent{
+		ID:               uuid.NewString(),
+		UserID:           userID,
+		ChatID:           chatID,
+		Model:            model.GetName(),
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             model.Cost(promptTokens, completionTokens),
+		CreatedAt:        time.Now(),
+	}
+}
+
+// UsageSummary aggregates the usage events of one model.
+type UsageSummary struct {
+	Model            string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
@@ -0,0 +1,14 @@
+package gateway
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"time"
+)
+
+type APIKeyGateway interface {
+	Create(ctx context.Context, key *entity.APIKey) error
+	FindById(ctx context.Context, id string) (*entity.APIKey, error)
+	Revoke(ctx context.Context, key *entity.APIKey) error
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) error
+}
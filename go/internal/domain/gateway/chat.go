@@ -2,11 +2,42 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"time"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ConflictError is returned by Save when the chat was changed by someone else
+// since it was loaded, i.e. its stored version no longer matches.
+type ConflictError struct {
+	ChatID  string
+	Version int
+}
+
+func (this *ConflictError) Error() string {
+	return fmt.Sprintf("chat %s was modified concurrently (expected version %d)", this.ChatID, this.Version)
+}
+
+// ChatListItem is the read model of a chat in a listing: the chat row alone,
+// without the messages and configuration an entity.Chat is built from.
+type ChatListItem struct {
+	ID         string
+	Status     string
+	TokenUsage int
+	Model      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
 type ChatGateway interface {
 	Create(ctx context.Context, chat *entity.Chat) error
 	FindById(ctx context.Context, id string) (*entity.Chat, error)
 	Save(ctx context.Context, chat *entity.Chat) error
+	Delete(ctx context.Context, id string) error
+	// ListByUser returns the chats of a user, most recently updated first. The
+	// returned cursor is empty when there are no more pages.
+	ListByUser(ctx context.Context, userID string, cursor string, limit int) ([]ChatListItem, string, error)
 }
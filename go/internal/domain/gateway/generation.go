@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrGenerationNotFound = errors.New("generation not found")
+
+// GenerationGateway tracks the running generations so a client can stop one by
+// id from another request.
+type GenerationGateway interface {
+	// Start registers a generation of userID. The returned context is cancelled
+	// by Cancel or with ctx; done must be called once the generation is over.
+	Start(ctx context.Context, userID string) (id string, generationCtx context.Context, done func())
+	// Cancel fails with ErrGenerationNotFound unless userID runs generation id.
+	Cancel(ctx context.Context, id string, userID string) error
+}
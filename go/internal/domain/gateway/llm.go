@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+)
+
+// LLMUsage is the token usage the provider billed for a completion.
+type LLMUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// FinishReasonStop is reported when the provider gives no finish reason, and
+// FinishReasonCancelled when the client stopped the generation.
+const (
+	FinishReasonStop      = "stop"
+	FinishReasonCancelled = "cancelled"
+)
+
+// LLMCompletion.Usage is nil when the provider does not report usage.
+type LLMCompletion struct {
+	Content      string
+	FinishReason string
+	Usage        *LLMUsage
+}
+
+// LLMChunk.FinishReason is only set on the last chunk of the answer and
+// LLMChunk.Usage on the last chunk of the stream, by providers that report them.
+// A stream stopped early ends without usage.
+type LLMChunk struct {
+	Content      string
+	FinishReason string
+	Usage        *LLMUsage
+}
+
+// LLMStream returns io.EOF from Recv once the provider has finished the answer.
+type LLMStream interface {
+	Recv() (*LLMChunk, error)
+	Close() error
+}
+
+type LLMGateway interface {
+	CreateChatCompletion(ctx context.Context, config *entity.ChatConfig, messages []*entity.Message) (*LLMCompletion, error)
+	CreateChatCompletionStream(ctx context.Context, config *entity.ChatConfig, messages []*entity.Message) (LLMStream, error)
+}
@@ -0,0 +1,15 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// QuotaGateway stores the usage counters of the quota windows. window is the
+// start of the minute or day the counter belongs to.
+type QuotaGateway interface {
+	// IncrementRequests counts one request and returns the count of the window.
+	IncrementRequests(ctx context.Context, userID string, window time.Time) (int, error)
+	TokensUsed(ctx context.Context, userID string, window time.Time) (int, error)
+	AddTokens(ctx context.Context, userID string, window time.Time, tokens int) error
+}
@@ -0,0 +1,13 @@
+package gateway
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"time"
+)
+
+type UsageGateway interface {
+	Record(ctx context.Context, event *entity.UsageEvent) error
+	// SummarizeByUser aggregates the events of userID created in [from, to) per model.
+	SummarizeByUser(ctx context.Context, userID string, from time.Time, to time.Time) ([]*entity.UsageSummary, error)
+}
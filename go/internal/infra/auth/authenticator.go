@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/authenticateapikey"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+)
+
+// Authenticator accepts bearer JWTs and API keys ("csk_..." bearer tokens).
+type Authenticator struct {
+	JWTVerifier               *JWTVerifier
+	AuthenticateAPIKeyUseCase *authenticateapikey.UseCase
+}
+
+func NewAuthenticator(
+	jwtVerifier *JWTVerifier,
+	authenticateAPIKeyUseCase *authenticateapikey.UseCase,
+) *Authenticator {
+	return &Authenticator{
+		JWTVerifier:               jwtVerifier,
+		AuthenticateAPIKeyUseCase: authenticateAPIKeyUseCase,
+	}
+}
+
+// Authenticate resolves the caller of an Authorization header. Rejected
+// credentials fail with ErrUnauthenticated; other errors are internal.
+func (this *Authenticator) Authenticate(ctx context.Context, header string) (*Principal, error) {
+	token, ok := bearerToken(header)
+	if !ok {
+		return nil, errors.Join(ErrUnauthenticated, errors.New("authorization must be a bearer token"))
+	}
+	if !entity.IsAPIKey(token) {
+		return this.JWTVerifier.Verify(token)
+	}
+	output, err := this.AuthenticateAPIKeyUseCase.Execute(authenticateapikey.InputDTO{Key: token}, ctx)
+	if errors.Is(err, entity.ErrInvalidAPIKey) {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		UserID: output.OwnerID,
+		Scopes: output.Scopes,
+		KeyID:  output.KeyID,
+	}, nil
+}
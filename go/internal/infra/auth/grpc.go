@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/interceptor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor authenticates the caller and checks it holds the scope of
+// the method in methodScopes. Methods missing from methodScopes are denied.
+func (this *Authenticator) UnaryInterceptor(methodScopes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := this.authorize(ctx, methodScopes[info.FullMethod])
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (this *Authenticator) StreamInterceptor(methodScopes map[string]string) grpc.StreamServerInterceptor {
+	return func(service any, serverStream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := this.authorize(serverStream.Context(), methodScopes[info.FullMethod])
+		if err != nil {
+			return err
+		}
+		return handler(service, interceptor.WithStreamContext(serverStream, ctx))
+	}
+}
+
+func (this *Authenticator) authorize(ctx context.Context, scope string) (context.Context, error) {
+	meta, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "metadata is not provided")
+	}
+	values := meta.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization token is not provided")
+	}
+	principal, err := this.Authenticate(ctx, values[0])
+	if errors.Is(err, ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization token")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if scope == "" || !principal.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "missing scope for this method")
+	}
+	return WithPrincipal(ctx, principal), nil
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Middleware rejects requests without valid credentials and stores the caller
+// in the request context.
+func (this *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		principal, err := this.Authenticate(req.Context(), req.Header.Get("Authorization"))
+		if errors.Is(err, ErrUnauthenticated) {
+			http.Error(res, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(res, req.WithContext(WithPrincipal(req.Context(), principal)))
+	})
+}
+
+// RequireScope only lets through callers granted scope.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		principal := PrincipalFromContext(req.Context())
+		if principal == nil || !principal.HasScope(scope) {
+			http.Error(res, "missing scope "+scope, http.StatusForbidden)
+			return
+		}
+		next(res, req)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// KeySet holds the verification keys by key id: *rsa.PublicKey for RS256 and
+// []byte secrets for HS256. The Go type of a key pins the algorithm it can
+// verify, so an RSA public key can never be used as an HMAC secret.
+type KeySet struct {
+	keys map[string]any
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys: make(map[string]any),
+	}
+}
+
+// LoadKeySet reads a JWKS file holding RSA public keys and/or oct secrets.
+func LoadKeySet(path string) (*KeySet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.Unmarshal(content, &document)
+	if err != nil {
+		return nil, errors.New("invalid jwks file: " + err.Error())
+	}
+	keySet := NewKeySet()
+	for _, key := range document.Keys {
+		err = keySet.addJWK(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks key %q: %w", key.Kid, err)
+		}
+	}
+	return keySet, nil
+}
+
+// AddSecret registers an HS256 secret under kid.
+func (this *KeySet) AddSecret(kid string, secret []byte) {
+	this.keys[kid] = secret
+}
+
+func (this *KeySet) Len() int {
+	return len(this.keys)
+}
+
+// Key returns the key of kid. Tokens without kid are accepted when the set
+// holds a single key.
+func (this *KeySet) Key(kid string) (any, bool) {
+	if kid == "" && len(this.keys) == 1 {
+		for _, key := range this.keys {
+			return key, true
+		}
+	}
+	key, ok := this.keys[kid]
+	return key, ok
+}
+
+func (this *KeySet) addJWK(key jwk) error {
+	if _, exists := this.keys[key.Kid]; exists {
+		return errors.New("duplicated kid")
+	}
+	switch key.Kty {
+	case "RSA":
+		if key.Alg != "" && key.Alg != "RS256" {
+			return errors.New("unsupported alg " + key.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return errors.New("invalid modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return errors.New("invalid exponent")
+		}
+		this.keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "oct":
+		if key.Alg != "" && key.Alg != "HS256" {
+			return errors.New("unsupported alg " + key.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(key.K)
+		if err != nil || len(secret) == 0 {
+			return errors.New("invalid secret")
+		}
+		this.keys[key.Kid] = secret
+	default:
+		return errors.New("unsupported kty " + key.Kty)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"strings"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// defaultScopes are granted to tokens without a scope claim.
+var defaultScopes = []string{entity.ScopeChatRead, entity.ScopeChatWrite}
+
+type claims struct {
+	jwt.RegisteredClaims
+	// Scope is the space separated OAuth 2.0 scope claim.
+	Scope string `json:"scope"`
+}
+
+// JWTVerifier validates HS256/RS256 tokens signed by one of its keys. The
+// user is the sub claim; issuer and audience are only checked when set.
+type JWTVerifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+}
+
+func NewJWTVerifier(keys *KeySet, issuer string, audience string) *JWTVerifier {
+	return &JWTVerifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+// Verify returns the caller of a valid token.
+func (this *JWTVerifier) Verify(token string) (*Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if this.issuer != "" {
+		options = append(options, jwt.WithIssuer(this.issuer))
+	}
+	if this.audience != "" {
+		options = append(options, jwt.WithAudience(this.audience))
+	}
+	parsed, err := jwt.ParseWithClaims(token, &claims{}, this.keyFor, options...)
+	if err != nil {
+		return nil, errors.Join(ErrUnauthenticated, err)
+	}
+	tokenClaims := parsed.Claims.(*claims)
+	if tokenClaims.Subject == "" {
+		return nil, errors.Join(ErrUnauthenticated, errors.New("token has no subject"))
+	}
+	scopes := defaultScopes
+	if tokenClaims.Scope != "" {
+		scopes = strings.Fields(tokenClaims.Scope)
+	}
+	return &Principal{
+		UserID: tokenClaims.Subject,
+		Scopes: scopes,
+	}, nil
+}
+
+func (this *JWTVerifier) keyFor(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := this.keys.Key(kid)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return key, nil
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var hmacSecret = []byte("a-test-secret-of-thirty-two-bytes")
+
+func testKeySet(t *testing.T) (*KeySet, *rsa.PrivateKey) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	keySet := NewKeySet()
+	keySet.AddSecret("hmac", hmacSecret)
+	err = keySet.addJWK(jwk{
+		Kty: "RSA",
+		Kid: "rsa",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+	})
+	if err != nil {
+		t.Fatalf("addJWK: %v", err)
+	}
+	return keySet, privateKey
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "user",
+		"iss": "issuer",
+		"aud": "chat",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	keySet, privateKey := testKeySet(t)
+	publicKeyBytes := x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)
+	verifier := NewJWTVerifier(keySet, "issuer", "chat")
+
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantScopes []string
+		wantErr    bool
+	}{
+		{
+			name:       "HS256 token with the shared secret",
+			token:      signToken(t, jwt.SigningMethodHS256, "hmac", validClaims(), hmacSecret),
+			wantScopes: []string{entity.ScopeChatRead, entity.ScopeChatWrite},
+		},
+		{
+			name:       "RS256 token with the scope claim",
+			token:      signToken(t, jwt.SigningMethodRS256, "rsa", withClaim("scope", "chat:read"), privateKey),
+			wantScopes: []string{"chat:read"},
+		},
+		{
+			name:    "HS256 token signed with the RSA public key",
+			token:   signToken(t, jwt.SigningMethodHS256, "rsa", validClaims(), publicKeyBytes),
+			wantErr: true,
+		},
+		{
+			name:    "RS256 token presented under the HMAC kid",
+			token:   signToken(t, jwt.SigningMethodRS256, "hmac", validClaims(), privateKey),
+			wantErr: true,
+		},
+		{
+			name:    "HS384 token",
+			token:   signToken(t, jwt.SigningMethodHS384, "hmac", validClaims(), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "unsigned token",
+			token:   signToken(t, jwt.SigningMethodNone, "hmac", validClaims(), jwt.UnsafeAllowNoneSignatureType),
+			wantErr: true,
+		},
+		{
+			name:    "wrong secret",
+			token:   signToken(t, jwt.SigningMethodHS256, "hmac", validClaims(), []byte("another-secret")),
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			token:   signToken(t, jwt.SigningMethodHS256, "other", validClaims(), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "kid required with several keys",
+			token:   signToken(t, jwt.SigningMethodHS256, "", validClaims(), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "expired token",
+			token:   signToken(t, jwt.SigningMethodHS256, "hmac", withClaim("exp", time.Now().Add(-time.Minute).Unix()), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "token without expiration",
+			token:   signToken(t, jwt.SigningMethodHS256, "hmac", withClaim("exp", nil), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "token without subject",
+			token:   signToken(t, jwt.SigningMethodHS256, "hmac", withClaim("sub", nil), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   signToken(t, jwt.SigningMethodHS256, "hmac", withClaim("iss", "someone-else"), hmacSecret),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   signToken(t, jwt.SigningMethodHS256, "hmac", withClaim("aud", "billing"), hmacSecret),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Fatalf("err = %v, want ErrUnauthenticated", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if principal.UserID != "user" {
+				t.Errorf("UserID = %s, want user", principal.UserID)
+			}
+			if !reflect.DeepEqual(principal.Scopes, tt.wantScopes) {
+				t.Errorf("Scopes = %v, want %v", principal.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
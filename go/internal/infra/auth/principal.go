@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"strings"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID string
+	Scopes []string
+	// KeyID is set when the caller used an API key.
+	KeyID string
+}
+
+func (this *Principal) HasScope(scope string) bool {
+	return entity.HasScope(this.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// UserIDFromContext returns the user authenticated for the request.
+func UserIDFromContext(ctx context.Context) string {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return ""
+	}
+	return principal.UserID
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer <token>" value.
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -5,36 +5,79 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+type ApiKey struct {
+	ID         string
+	Name       string
+	OwnerID    string
+	KeyHash    string
+	Scopes     json.RawMessage
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
 type Chat struct {
+	ID                 string
+	UserID             string
+	InitialMessageID   string
+	Status             string
+	Temperature        float64
+	TopP               float64
+	N                  int32
+	PresencePenalty    float64
+	FrequencyPenalty   float64
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Version            int32
+	Stop               json.RawMessage
+	TruncationStrategy string
+	KeepLastTurns      int32
+	ReserveReplyTokens bool
+	Summarize          bool
+	SummaryMessageID   string
+	SummarySourceIds   json.RawMessage
+	TokenUsage         int32
+	Model              string
+	ModelMaxTokens     int32
+	MaxTokens          int32
+}
+
+type Message struct {
 	ID               string
-	UserID           string
-	InitialMessageID string
-	Status           string
-	TokenUsage       int32
-	Model            string
-	ModelMaxTokens   int32
-	Temperature      float64
-	TopP             float64
-	N                int32
-	Stop             string
-	MaxTokens        int32
-	PresencePenalty  float64
-	FrequencyPenalty float64
+	ChatID           string
+	Role             string
+	Content          string
+	Erased           bool
+	OrderMsg         int32
 	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	PromptTokens     int32
+	CompletionTokens int32
+	Truncated        bool
+	ArchivedAt       sql.NullTime
+	Tokens           int32
+	Model            string
 }
 
-type Message struct {
-	ID        string
-	ChatID    string
-	Role      string
-	Content   string
-	Tokens    int32
-	Model     string
-	Erased    bool
-	OrderMsg  int32
-	CreatedAt time.Time
+type QuotaCounter struct {
+	UserID      string
+	Counter     string
+	WindowStart time.Time
+	Value       int64
+}
+
+type UsageEvent struct {
+	ID               string
+	UserID           string
+	ChatID           string
+	Model            string
+	PromptTokens     int32
+	CompletionTokens int32
+	Cost             float64
+	CreatedAt        time.Time
 }
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -19,20 +21,28 @@ INSERT INTO messages (id,
                       model,
                       erased,
                       order_msg,
-                      created_at)
-VALUES (?,?,?,?,?,?,?,?,?)
+                      created_at,
+                      prompt_tokens,
+                      completion_tokens,
+                      truncated,
+                      archived_at)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)
 `
 
 type AddMessageParams struct {
-	ID        string
-	ChatID    string
-	Role      string
-	Content   string
-	Tokens    int32
-	Model     string
-	Erased    bool
-	OrderMsg  int32
-	CreatedAt time.Time
+	ID               string
+	ChatID           string
+	Role             string
+	Content          string
+	Tokens           int32
+	Model            string
+	Erased           bool
+	OrderMsg         int32
+	CreatedAt        time.Time
+	PromptTokens     int32
+	CompletionTokens int32
+	Truncated        bool
+	ArchivedAt       sql.NullTime
 }
 
 func (q *Queries) AddMessage(ctx context.Context, arg AddMessageParams) error {
@@ -46,6 +56,50 @@ func (q *Queries) AddMessage(ctx context.Context, arg AddMessageParams) error {
 		arg.Erased,
 		arg.OrderMsg,
 		arg.CreatedAt,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Truncated,
+		arg.ArchivedAt,
+	)
+	return err
+}
+
+const createAPIKey = `-- name: CreateAPIKey :exec
+INSERT INTO api_keys (id,
+                      name,
+                      owner_id,
+                      key_hash,
+                      scopes,
+                      expires_at,
+                      last_used_at,
+                      revoked_at,
+                      created_at)
+VALUES (?,?,?,?,?,?,?,?,?)
+`
+
+type CreateAPIKeyParams struct {
+	ID         string
+	Name       string
+	OwnerID    string
+	KeyHash    string
+	Scopes     json.RawMessage
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) error {
+	_, err := q.db.ExecContext(ctx, createAPIKey,
+		arg.ID,
+		arg.Name,
+		arg.OwnerID,
+		arg.KeyHash,
+		arg.Scopes,
+		arg.ExpiresAt,
+		arg.LastUsedAt,
+		arg.RevokedAt,
+		arg.CreatedAt,
 	)
 	return err
 }
@@ -66,27 +120,41 @@ INSERT INTO chats (id,
                    presence_penalty,
                    frequency_penalty,
                    created_at,
-                   updated_at)
-VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+                   updated_at,
+                   version,
+                   truncation_strategy,
+                   keep_last_turns,
+                   reserve_reply_tokens,
+                   summarize,
+                   summary_message_id,
+                   summary_source_ids)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
 `
 
 type CreateChatParams struct {
-	ID               string
-	UserID           string
-	InitialMessageID string
-	Status           string
-	TokenUsage       int32
-	Model            string
-	ModelMaxTokens   int32
-	Temperature      float64
-	TopP             float64
-	N                int32
-	Stop             string
-	MaxTokens        int32
-	PresencePenalty  float64
-	FrequencyPenalty float64
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	ID                 string
+	UserID             string
+	InitialMessageID   string
+	Status             string
+	TokenUsage         int32
+	Model              string
+	ModelMaxTokens     int32
+	Temperature        float64
+	TopP               float64
+	N                  int32
+	Stop               json.RawMessage
+	MaxTokens          int32
+	PresencePenalty    float64
+	FrequencyPenalty   float64
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Version            int32
+	TruncationStrategy string
+	KeepLastTurns      int32
+	ReserveReplyTokens bool
+	Summarize          bool
+	SummaryMessageID   string
+	SummarySourceIds   json.RawMessage
 }
 
 func (q *Queries) CreateChat(ctx context.Context, arg CreateChatParams) error {
@@ -107,30 +175,156 @@ func (q *Queries) CreateChat(ctx context.Context, arg CreateChatParams) error {
 		arg.FrequencyPenalty,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.Version,
+		arg.TruncationStrategy,
+		arg.KeepLastTurns,
+		arg.ReserveReplyTokens,
+		arg.Summarize,
+		arg.SummaryMessageID,
+		arg.SummarySourceIds,
+	)
+	return err
+}
+
+const createUsageEvent = `-- name: CreateUsageEvent :exec
+INSERT INTO usage_events (id,
+                          user_id,
+                          chat_id,
+                          model,
+                          prompt_tokens,
+                          completion_tokens,
+                          cost,
+                          created_at)
+VALUES (?,?,?,?,?,?,?,?)
+`
+
+type CreateUsageEventParams struct {
+	ID               string
+	UserID           string
+	ChatID           string
+	Model            string
+	PromptTokens     int32
+	CompletionTokens int32
+	Cost             float64
+	CreatedAt        time.Time
+}
+
+func (q *Queries) CreateUsageEvent(ctx context.Context, arg CreateUsageEventParams) error {
+	_, err := q.db.ExecContext(ctx, createUsageEvent,
+		arg.ID,
+		arg.UserID,
+		arg.ChatID,
+		arg.Model,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Cost,
+		arg.CreatedAt,
 	)
 	return err
 }
 
-const deleteChatMessages = `-- name: DeleteChatMessages :exec
-DELETE FROM messages WHERE chat_id = ?
+const deleteChat = `-- name: DeleteChat :exec
+DELETE FROM chats WHERE id = ?
 `
 
-func (q *Queries) DeleteChatMessages(ctx context.Context, chatID string) error {
-	_, err := q.db.ExecContext(ctx, deleteChatMessages, chatID)
+func (q *Queries) DeleteChat(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, deleteChat, id)
 	return err
 }
 
-const deleteErasedChatMessages = `-- name: DeleteErasedChatMessages :exec
-DELETE FROM messages WHERE erased=1 and chat_id = ?
+const deleteMessage = `-- name: DeleteMessage :exec
+DELETE FROM messages WHERE id = ? AND chat_id = ?
 `
 
-func (q *Queries) DeleteErasedChatMessages(ctx context.Context, chatID string) error {
-	_, err := q.db.ExecContext(ctx, deleteErasedChatMessages, chatID)
+type DeleteMessageParams struct {
+	ID     string
+	ChatID string
+}
+
+func (q *Queries) DeleteMessage(ctx context.Context, arg DeleteMessageParams) error {
+	_, err := q.db.ExecContext(ctx, deleteMessage, arg.ID, arg.ChatID)
 	return err
 }
 
+const deleteQuotaCountersBefore = `-- name: DeleteQuotaCountersBefore :exec
+DELETE FROM quota_counters WHERE user_id = ? AND counter = ? AND window_start < ?
+`
+
+type DeleteQuotaCountersBeforeParams struct {
+	UserID      string
+	Counter     string
+	WindowStart time.Time
+}
+
+func (q *Queries) DeleteQuotaCountersBefore(ctx context.Context, arg DeleteQuotaCountersBeforeParams) error {
+	_, err := q.db.ExecContext(ctx, deleteQuotaCountersBefore, arg.UserID, arg.Counter, arg.WindowStart)
+	return err
+}
+
+const findAPIKeyById = `-- name: FindAPIKeyById :one
+SELECT id, name, owner_id, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_keys WHERE id = ?
+`
+
+func (q *Queries) FindAPIKeyById(ctx context.Context, id string) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, findAPIKeyById, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerID,
+		&i.KeyHash,
+		&i.Scopes,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const findArchivedMessagesByChatId = `-- name: FindArchivedMessagesByChatId :many
+SELECT id, chat_id, role, content, erased, order_msg, created_at, prompt_tokens, completion_tokens, truncated, archived_at, tokens, model FROM messages WHERE archived_at IS NOT NULL AND chat_id = ? ORDER BY order_msg ASC
+`
+
+func (q *Queries) FindArchivedMessagesByChatId(ctx context.Context, chatID string) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, findArchivedMessagesByChatId, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChatID,
+			&i.Role,
+			&i.Content,
+			&i.Erased,
+			&i.OrderMsg,
+			&i.CreatedAt,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Truncated,
+			&i.ArchivedAt,
+			&i.Tokens,
+			&i.Model,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const findChatById = `-- name: FindChatById :one
-SELECT id, user_id, initial_message_id, status, token_usage, model, model_max_tokens, temperature, top_p, n, stop, max_tokens, presence_penalty, frequency_penalty, created_at, updated_at FROM chats WHERE id = ?
+SELECT id, user_id, initial_message_id, status, temperature, top_p, n, presence_penalty, frequency_penalty, created_at, updated_at, version, stop, truncation_strategy, keep_last_turns, reserve_reply_tokens, summarize, summary_message_id, summary_source_ids, token_usage, model, model_max_tokens, max_tokens FROM chats WHERE id = ?
 `
 
 func (q *Queries) FindChatById(ctx context.Context, id string) (Chat, error) {
@@ -141,24 +335,31 @@ func (q *Queries) FindChatById(ctx context.Context, id string) (Chat, error) {
 		&i.UserID,
 		&i.InitialMessageID,
 		&i.Status,
-		&i.TokenUsage,
-		&i.Model,
-		&i.ModelMaxTokens,
 		&i.Temperature,
 		&i.TopP,
 		&i.N,
-		&i.Stop,
-		&i.MaxTokens,
 		&i.PresencePenalty,
 		&i.FrequencyPenalty,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.Stop,
+		&i.TruncationStrategy,
+		&i.KeepLastTurns,
+		&i.ReserveReplyTokens,
+		&i.Summarize,
+		&i.SummaryMessageID,
+		&i.SummarySourceIds,
+		&i.TokenUsage,
+		&i.Model,
+		&i.ModelMaxTokens,
+		&i.MaxTokens,
 	)
 	return i, err
 }
 
 const findErasedMessagesByChatId = `-- name: FindErasedMessagesByChatId :many
-SELECT id, chat_id, role, content, tokens, model, erased, order_msg, created_at FROM messages WHERE erased=1 AND chat_id = ? ORDER BY order_msg ASC
+SELECT id, chat_id, role, content, erased, order_msg, created_at, prompt_tokens, completion_tokens, truncated, archived_at, tokens, model FROM messages WHERE erased=1 AND archived_at IS NULL AND chat_id = ? ORDER BY order_msg ASC
 `
 
 func (q *Queries) FindErasedMessagesByChatId(ctx context.Context, chatID string) ([]Message, error) {
@@ -175,11 +376,54 @@ func (q *Queries) FindErasedMessagesByChatId(ctx context.Context, chatID string)
 			&i.ChatID,
 			&i.Role,
 			&i.Content,
+			&i.Erased,
+			&i.OrderMsg,
+			&i.CreatedAt,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Truncated,
+			&i.ArchivedAt,
 			&i.Tokens,
 			&i.Model,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findMessagePositionsByChatId = `-- name: FindMessagePositionsByChatId :many
+SELECT id, erased, order_msg, archived_at FROM messages WHERE chat_id = ?
+`
+
+type FindMessagePositionsByChatIdRow struct {
+	ID         string
+	Erased     bool
+	OrderMsg   int32
+	ArchivedAt sql.NullTime
+}
+
+func (q *Queries) FindMessagePositionsByChatId(ctx context.Context, chatID string) ([]FindMessagePositionsByChatIdRow, error) {
+	rows, err := q.db.QueryContext(ctx, findMessagePositionsByChatId, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindMessagePositionsByChatIdRow
+	for rows.Next() {
+		var i FindMessagePositionsByChatIdRow
+		if err := rows.Scan(
+			&i.ID,
 			&i.Erased,
 			&i.OrderMsg,
-			&i.CreatedAt,
+			&i.ArchivedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -195,7 +439,7 @@ func (q *Queries) FindErasedMessagesByChatId(ctx context.Context, chatID string)
 }
 
 const findMessagesByChatId = `-- name: FindMessagesByChatId :many
-SELECT id, chat_id, role, content, tokens, model, erased, order_msg, created_at FROM messages WHERE erased=0 AND chat_id = ? ORDER BY order_msg ASC
+SELECT id, chat_id, role, content, erased, order_msg, created_at, prompt_tokens, completion_tokens, truncated, archived_at, tokens, model FROM messages WHERE erased=0 AND archived_at IS NULL AND chat_id = ? ORDER BY order_msg ASC
 `
 
 func (q *Queries) FindMessagesByChatId(ctx context.Context, chatID string) ([]Message, error) {
@@ -212,11 +456,114 @@ func (q *Queries) FindMessagesByChatId(ctx context.Context, chatID string) ([]Me
 			&i.ChatID,
 			&i.Role,
 			&i.Content,
-			&i.Tokens,
-			&i.Model,
 			&i.Erased,
 			&i.OrderMsg,
 			&i.CreatedAt,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Truncated,
+			&i.ArchivedAt,
+			&i.Tokens,
+			&i.Model,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getQuotaCounter = `-- name: GetQuotaCounter :one
+SELECT value FROM quota_counters WHERE user_id = ? AND counter = ? AND window_start = ?
+`
+
+type GetQuotaCounterParams struct {
+	UserID      string
+	Counter     string
+	WindowStart time.Time
+}
+
+func (q *Queries) GetQuotaCounter(ctx context.Context, arg GetQuotaCounterParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getQuotaCounter, arg.UserID, arg.Counter, arg.WindowStart)
+	var value int64
+	err := row.Scan(&value)
+	return value, err
+}
+
+const incrementQuotaCounter = `-- name: IncrementQuotaCounter :exec
+INSERT INTO quota_counters (user_id, counter, window_start, value)
+VALUES (?,?,?,?)
+ON DUPLICATE KEY UPDATE value = value + VALUES(value)
+`
+
+type IncrementQuotaCounterParams struct {
+	UserID      string
+	Counter     string
+	WindowStart time.Time
+	Value       int64
+}
+
+func (q *Queries) IncrementQuotaCounter(ctx context.Context, arg IncrementQuotaCounterParams) error {
+	_, err := q.db.ExecContext(ctx, incrementQuotaCounter,
+		arg.UserID,
+		arg.Counter,
+		arg.WindowStart,
+		arg.Value,
+	)
+	return err
+}
+
+const listChatsByUser = `-- name: ListChatsByUser :many
+SELECT id, user_id, initial_message_id, status, temperature, top_p, n, presence_penalty, frequency_penalty, created_at, updated_at, version, stop, truncation_strategy, keep_last_turns, reserve_reply_tokens, summarize, summary_message_id, summary_source_ids, token_usage, model, model_max_tokens, max_tokens FROM chats
+WHERE user_id = ?
+ORDER BY updated_at DESC, id DESC
+LIMIT ?
+`
+
+type ListChatsByUserParams struct {
+	UserID string
+	Limit  int32
+}
+
+func (q *Queries) ListChatsByUser(ctx context.Context, arg ListChatsByUserParams) ([]Chat, error) {
+	rows, err := q.db.QueryContext(ctx, listChatsByUser, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chat
+	for rows.Next() {
+		var i Chat
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.InitialMessageID,
+			&i.Status,
+			&i.Temperature,
+			&i.TopP,
+			&i.N,
+			&i.PresencePenalty,
+			&i.FrequencyPenalty,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Stop,
+			&i.TruncationStrategy,
+			&i.KeepLastTurns,
+			&i.ReserveReplyTokens,
+			&i.Summarize,
+			&i.SummaryMessageID,
+			&i.SummarySourceIds,
+			&i.TokenUsage,
+			&i.Model,
+			&i.ModelMaxTokens,
+			&i.MaxTokens,
 		); err != nil {
 			return nil, err
 		}
@@ -231,7 +578,104 @@ func (q *Queries) FindMessagesByChatId(ctx context.Context, chatID string) ([]Me
 	return items, nil
 }
 
-const saveChat = `-- name: SaveChat :exec
+const listChatsByUserAfter = `-- name: ListChatsByUserAfter :many
+SELECT id, user_id, initial_message_id, status, temperature, top_p, n, presence_penalty, frequency_penalty, created_at, updated_at, version, stop, truncation_strategy, keep_last_turns, reserve_reply_tokens, summarize, summary_message_id, summary_source_ids, token_usage, model, model_max_tokens, max_tokens FROM chats
+WHERE user_id = ?
+  AND (updated_at < ?
+    OR (updated_at = ? AND id < ?))
+ORDER BY updated_at DESC, id DESC
+LIMIT ?
+`
+
+type ListChatsByUserAfterParams struct {
+	UserID          string
+	CursorUpdatedAt time.Time
+	CursorID        string
+	Limit           int32
+}
+
+func (q *Queries) ListChatsByUserAfter(ctx context.Context, arg ListChatsByUserAfterParams) ([]Chat, error) {
+	rows, err := q.db.QueryContext(ctx, listChatsByUserAfter,
+		arg.UserID,
+		arg.CursorUpdatedAt,
+		arg.CursorUpdatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chat
+	for rows.Next() {
+		var i Chat
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.InitialMessageID,
+			&i.Status,
+			&i.Temperature,
+			&i.TopP,
+			&i.N,
+			&i.PresencePenalty,
+			&i.FrequencyPenalty,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Stop,
+			&i.TruncationStrategy,
+			&i.KeepLastTurns,
+			&i.ReserveReplyTokens,
+			&i.Summarize,
+			&i.SummaryMessageID,
+			&i.SummarySourceIds,
+			&i.TokenUsage,
+			&i.Model,
+			&i.ModelMaxTokens,
+			&i.MaxTokens,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAPIKeyUsed = `-- name: MarkAPIKeyUsed :exec
+UPDATE api_keys SET last_used_at = ? WHERE id = ?
+`
+
+type MarkAPIKeyUsedParams struct {
+	LastUsedAt sql.NullTime
+	ID         string
+}
+
+func (q *Queries) MarkAPIKeyUsed(ctx context.Context, arg MarkAPIKeyUsedParams) error {
+	_, err := q.db.ExecContext(ctx, markAPIKeyUsed, arg.LastUsedAt, arg.ID)
+	return err
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :exec
+UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL
+`
+
+type RevokeAPIKeyParams struct {
+	RevokedAt sql.NullTime
+	ID        string
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) error {
+	_, err := q.db.ExecContext(ctx, revokeAPIKey, arg.RevokedAt, arg.ID)
+	return err
+}
+
+const saveChat = `-- name: SaveChat :execrows
 UPDATE chats SET
                  user_id = ?,
                  initial_message_id = ?,
@@ -246,30 +690,44 @@ UPDATE chats SET
                  max_tokens = ?,
                  presence_penalty = ?,
                  frequency_penalty = ?,
-                 updated_at = ?
-    WHERE id = ?
+                 truncation_strategy = ?,
+                 keep_last_turns = ?,
+                 reserve_reply_tokens = ?,
+                 summarize = ?,
+                 summary_message_id = ?,
+                 summary_source_ids = ?,
+                 updated_at = ?,
+                 version = version + 1
+    WHERE id = ? AND version = ?
 `
 
 type SaveChatParams struct {
-	UserID           string
-	InitialMessageID string
-	Status           string
-	TokenUsage       int32
-	Model            string
-	ModelMaxTokens   int32
-	Temperature      float64
-	TopP             float64
-	N                int32
-	Stop             string
-	MaxTokens        int32
-	PresencePenalty  float64
-	FrequencyPenalty float64
-	UpdatedAt        time.Time
-	ID               string
+	UserID             string
+	InitialMessageID   string
+	Status             string
+	TokenUsage         int32
+	Model              string
+	ModelMaxTokens     int32
+	Temperature        float64
+	TopP               float64
+	N                  int32
+	Stop               json.RawMessage
+	MaxTokens          int32
+	PresencePenalty    float64
+	FrequencyPenalty   float64
+	TruncationStrategy string
+	KeepLastTurns      int32
+	ReserveReplyTokens bool
+	Summarize          bool
+	SummaryMessageID   string
+	SummarySourceIds   json.RawMessage
+	UpdatedAt          time.Time
+	ID                 string
+	Version            int32
 }
 
-func (q *Queries) SaveChat(ctx context.Context, arg SaveChatParams) error {
-	_, err := q.db.ExecContext(ctx, saveChat,
+func (q *Queries) SaveChat(ctx context.Context, arg SaveChatParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, saveChat,
 		arg.UserID,
 		arg.InitialMessageID,
 		arg.Status,
@@ -283,8 +741,98 @@ func (q *Queries) SaveChat(ctx context.Context, arg SaveChatParams) error {
 		arg.MaxTokens,
 		arg.PresencePenalty,
 		arg.FrequencyPenalty,
+		arg.TruncationStrategy,
+		arg.KeepLastTurns,
+		arg.ReserveReplyTokens,
+		arg.Summarize,
+		arg.SummaryMessageID,
+		arg.SummarySourceIds,
 		arg.UpdatedAt,
 		arg.ID,
+		arg.Version,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const summarizeUsageByUser = `-- name: SummarizeUsageByUser :many
+SELECT model,
+       COUNT(*) AS requests,
+       CAST(SUM(prompt_tokens) AS SIGNED) AS prompt_tokens,
+       CAST(SUM(completion_tokens) AS SIGNED) AS completion_tokens,
+       CAST(SUM(cost) AS DECIMAL(20,8)) AS cost
+FROM usage_events
+WHERE user_id = ?
+  AND created_at >= ?
+  AND created_at < ?
+GROUP BY model
+ORDER BY model
+`
+
+type SummarizeUsageByUserParams struct {
+	UserID   string
+	FromTime time.Time
+	ToTime   time.Time
+}
+
+type SummarizeUsageByUserRow struct {
+	Model            string
+	Requests         int64
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+}
+
+func (q *Queries) SummarizeUsageByUser(ctx context.Context, arg SummarizeUsageByUserParams) ([]SummarizeUsageByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, summarizeUsageByUser, arg.UserID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SummarizeUsageByUserRow
+	for rows.Next() {
+		var i SummarizeUsageByUserRow
+		if err := rows.Scan(
+			&i.Model,
+			&i.Requests,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateMessagePosition = `-- name: UpdateMessagePosition :exec
+UPDATE messages SET erased = ?, order_msg = ?, archived_at = ? WHERE id = ? AND chat_id = ?
+`
+
+type UpdateMessagePositionParams struct {
+	Erased     bool
+	OrderMsg   int32
+	ArchivedAt sql.NullTime
+	ID         string
+	ChatID     string
+}
+
+func (q *Queries) UpdateMessagePosition(ctx context.Context, arg UpdateMessagePositionParams) error {
+	_, err := q.db.ExecContext(ctx, updateMessagePosition,
+		arg.Erased,
+		arg.OrderMsg,
+		arg.ArchivedAt,
+		arg.ID,
+		arg.ChatID,
 	)
 	return err
 }
@@ -0,0 +1,53 @@
+package generation
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"sync"
+)
+
+// MemoryRegistry tracks the generations running in the process, so Cancel only
+// reaches generations of the instance serving the request.
+type MemoryRegistry struct {
+	mutex       sync.Mutex
+	generations map[string]*running
+}
+
+type running struct {
+	userID string
+	cancel context.CancelFunc
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		generations: make(map[string]*running),
+	}
+}
+
+func (this *MemoryRegistry) Start(ctx context.Context, userID string) (string, context.Context, func()) {
+	id := uuid.NewString()
+	generationCtx, cancel := context.WithCancel(ctx)
+	this.mutex.Lock()
+	this.generations[id] = &running{userID: userID, cancel: cancel}
+	this.mutex.Unlock()
+	done := func() {
+		this.mutex.Lock()
+		delete(this.generations, id)
+		this.mutex.Unlock()
+		cancel()
+	}
+	return id, generationCtx, done
+}
+
+func (this *MemoryRegistry) Cancel(ctx context.Context, id string, userID string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	generation, found := this.generations[id]
+	// a generation of another user is reported as missing, not forbidden
+	if !found || generation.userID != userID {
+		return gateway.ErrGenerationNotFound
+	}
+	generation.cancel()
+	return nil
+}
@@ -0,0 +1,47 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+// UnaryLogging logs every call with its status code and duration.
+func UnaryLogging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func StreamLogging(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(service any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(service, stream)
+		logCall(stream.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	attributes := []any{
+		"method", method,
+		"code", code.String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+		"request_id", RequestIDFromContext(ctx),
+	}
+	if err != nil {
+		attributes = append(attributes, "error", status.Convert(err).Message())
+	}
+	logger.Log(ctx, level, "grpc call", attributes...)
+}
@@ -0,0 +1,42 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+)
+
+// UnaryRecovery turns a panic of the handler into a codes.Internal error
+// instead of crashing the server.
+func UnaryRecovery(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer RecoverPanic(ctx, logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(service any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer RecoverPanic(stream.Context(), logger, info.FullMethod, &err)
+		return handler(service, stream)
+	}
+}
+
+// RecoverPanic must be deferred directly. It also guards the goroutines a
+// handler starts, which the interceptors do not cover.
+func RecoverPanic(ctx context.Context, logger *slog.Logger, method string, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	logger.ErrorContext(ctx, "grpc handler panicked",
+		"method", method,
+		"request_id", RequestIDFromContext(ctx),
+		"panic", recovered,
+		"stack", string(debug.Stack()),
+	)
+	*err = status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,45 @@
+package interceptor
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const requestIDHeader = "x-request-id"
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the id of the current request, "" outside one.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// withRequestID reuses the x-request-id sent by the client or generates one
+// and stores it in the context. Callers echo it in the response headers.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var requestID string
+	if meta, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := meta.Get(requestIDHeader); len(values) > 0 && values[0] != "" {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID), requestID
+}
+
+func UnaryRequestID(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, requestID := withRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+	return handler(ctx, req)
+}
+
+func StreamRequestID(service any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, requestID := withRequestID(stream.Context())
+	stream.SetHeader(metadata.Pairs(requestIDHeader, requestID))
+	return handler(service, WithStreamContext(stream, ctx))
+}
@@ -0,0 +1,22 @@
+package interceptor
+
+import (
+	"context"
+	"google.golang.org/grpc"
+)
+
+// contextStream replaces the context of a server stream, so a stream
+// interceptor can hand values down the chain.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// WithStreamContext returns stream with ctx as its context.
+func WithStreamContext(stream grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	return &contextStream{ServerStream: stream, ctx: ctx}
+}
+
+func (this *contextStream) Context() context.Context {
+	return this.ctx
+}
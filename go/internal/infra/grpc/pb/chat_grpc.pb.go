@@ -23,6 +23,23 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChatServiceClient interface {
 	ChatStream(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (ChatService_ChatStreamClient, error)
+	Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error)
+	// Converse answers every message of the stream in order, one at a time.
+	Converse(ctx context.Context, opts ...grpc.CallOption) (ChatService_ConverseClient, error)
+	// CancelGeneration stops a running generation of the caller; the partial
+	// answer is saved as truncated and the stream ends with a cancelled frame.
+	CancelGeneration(ctx context.Context, in *CancelGenerationRequest, opts ...grpc.CallOption) (*CancelGenerationResponse, error)
+	// Regenerate archives the last answer and streams a new one like ChatStream.
+	Regenerate(ctx context.Context, in *RegenerateRequest, opts ...grpc.CallOption) (ChatService_RegenerateClient, error)
+	// EditMessage archives the last user message and its answer, then streams
+	// the answer to the new version like ChatStream.
+	EditMessage(ctx context.Context, in *EditMessageRequest, opts ...grpc.CallOption) (ChatService_EditMessageClient, error)
+	GetChat(ctx context.Context, in *GetChatRequest, opts ...grpc.CallOption) (*GetChatResponse, error)
+	ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error)
+	ListChats(ctx context.Context, in *ListChatsRequest, opts ...grpc.CallOption) (*ListChatsResponse, error)
+	CloseChat(ctx context.Context, in *ChatStatusRequest, opts ...grpc.CallOption) (*ChatStatusResponse, error)
+	ReopenChat(ctx context.Context, in *ChatStatusRequest, opts ...grpc.CallOption) (*ChatStatusResponse, error)
+	DeleteChat(ctx context.Context, in *DeleteChatRequest, opts ...grpc.CallOption) (*DeleteChatResponse, error)
 }
 
 type chatServiceClient struct {
@@ -65,11 +82,195 @@ func (x *chatServiceChatStreamClient) Recv() (*ChatResponse, error) {
 	return m, nil
 }
 
+func (c *chatServiceClient) Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error) {
+	out := new(ChatResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/Chat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) Converse(ctx context.Context, opts ...grpc.CallOption) (ChatService_ConverseClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[1], "/pb.ChatService/Converse", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceConverseClient{stream}
+	return x, nil
+}
+
+type ChatService_ConverseClient interface {
+	Send(*ConverseRequest) error
+	Recv() (*ChatResponse, error)
+	grpc.ClientStream
+}
+
+type chatServiceConverseClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceConverseClient) Send(m *ConverseRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *chatServiceConverseClient) Recv() (*ChatResponse, error) {
+	m := new(ChatResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) CancelGeneration(ctx context.Context, in *CancelGenerationRequest, opts ...grpc.CallOption) (*CancelGenerationResponse, error) {
+	out := new(CancelGenerationResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/CancelGeneration", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) Regenerate(ctx context.Context, in *RegenerateRequest, opts ...grpc.CallOption) (ChatService_RegenerateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[2], "/pb.ChatService/Regenerate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceRegenerateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_RegenerateClient interface {
+	Recv() (*ChatResponse, error)
+	grpc.ClientStream
+}
+
+type chatServiceRegenerateClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceRegenerateClient) Recv() (*ChatResponse, error) {
+	m := new(ChatResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) EditMessage(ctx context.Context, in *EditMessageRequest, opts ...grpc.CallOption) (ChatService_EditMessageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[3], "/pb.ChatService/EditMessage", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceEditMessageClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_EditMessageClient interface {
+	Recv() (*ChatResponse, error)
+	grpc.ClientStream
+}
+
+type chatServiceEditMessageClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceEditMessageClient) Recv() (*ChatResponse, error) {
+	m := new(ChatResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) GetChat(ctx context.Context, in *GetChatRequest, opts ...grpc.CallOption) (*GetChatResponse, error) {
+	out := new(GetChatResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/GetChat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListMessages(ctx context.Context, in *ListMessagesRequest, opts ...grpc.CallOption) (*ListMessagesResponse, error) {
+	out := new(ListMessagesResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/ListMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListChats(ctx context.Context, in *ListChatsRequest, opts ...grpc.CallOption) (*ListChatsResponse, error) {
+	out := new(ListChatsResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/ListChats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) CloseChat(ctx context.Context, in *ChatStatusRequest, opts ...grpc.CallOption) (*ChatStatusResponse, error) {
+	out := new(ChatStatusResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/CloseChat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ReopenChat(ctx context.Context, in *ChatStatusRequest, opts ...grpc.CallOption) (*ChatStatusResponse, error) {
+	out := new(ChatStatusResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/ReopenChat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) DeleteChat(ctx context.Context, in *DeleteChatRequest, opts ...grpc.CallOption) (*DeleteChatResponse, error) {
+	out := new(DeleteChatResponse)
+	err := c.cc.Invoke(ctx, "/pb.ChatService/DeleteChat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServiceServer is the server API for ChatService service.
 // All implementations must embed UnimplementedChatServiceServer
 // for forward compatibility
 type ChatServiceServer interface {
 	ChatStream(*ChatRequest, ChatService_ChatStreamServer) error
+	Chat(context.Context, *ChatRequest) (*ChatResponse, error)
+	// Converse answers every message of the stream in order, one at a time.
+	Converse(ChatService_ConverseServer) error
+	// CancelGeneration stops a running generation of the caller; the partial
+	// answer is saved as truncated and the stream ends with a cancelled frame.
+	CancelGeneration(context.Context, *CancelGenerationRequest) (*CancelGenerationResponse, error)
+	// Regenerate archives the last answer and streams a new one like ChatStream.
+	Regenerate(*RegenerateRequest, ChatService_RegenerateServer) error
+	// EditMessage archives the last user message and its answer, then streams
+	// the answer to the new version like ChatStream.
+	EditMessage(*EditMessageRequest, ChatService_EditMessageServer) error
+	GetChat(context.Context, *GetChatRequest) (*GetChatResponse, error)
+	ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error)
+	ListChats(context.Context, *ListChatsRequest) (*ListChatsResponse, error)
+	CloseChat(context.Context, *ChatStatusRequest) (*ChatStatusResponse, error)
+	ReopenChat(context.Context, *ChatStatusRequest) (*ChatStatusResponse, error)
+	DeleteChat(context.Context, *DeleteChatRequest) (*DeleteChatResponse, error)
 	mustEmbedUnimplementedChatServiceServer()
 }
 
@@ -80,6 +281,39 @@ type UnimplementedChatServiceServer struct {
 func (UnimplementedChatServiceServer) ChatStream(*ChatRequest, ChatService_ChatStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method ChatStream not implemented")
 }
+func (UnimplementedChatServiceServer) Chat(context.Context, *ChatRequest) (*ChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedChatServiceServer) Converse(ChatService_ConverseServer) error {
+	return status.Errorf(codes.Unimplemented, "method Converse not implemented")
+}
+func (UnimplementedChatServiceServer) CancelGeneration(context.Context, *CancelGenerationRequest) (*CancelGenerationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelGeneration not implemented")
+}
+func (UnimplementedChatServiceServer) Regenerate(*RegenerateRequest, ChatService_RegenerateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Regenerate not implemented")
+}
+func (UnimplementedChatServiceServer) EditMessage(*EditMessageRequest, ChatService_EditMessageServer) error {
+	return status.Errorf(codes.Unimplemented, "method EditMessage not implemented")
+}
+func (UnimplementedChatServiceServer) GetChat(context.Context, *GetChatRequest) (*GetChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChat not implemented")
+}
+func (UnimplementedChatServiceServer) ListMessages(context.Context, *ListMessagesRequest) (*ListMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMessages not implemented")
+}
+func (UnimplementedChatServiceServer) ListChats(context.Context, *ListChatsRequest) (*ListChatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChats not implemented")
+}
+func (UnimplementedChatServiceServer) CloseChat(context.Context, *ChatStatusRequest) (*ChatStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseChat not implemented")
+}
+func (UnimplementedChatServiceServer) ReopenChat(context.Context, *ChatStatusRequest) (*ChatStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReopenChat not implemented")
+}
+func (UnimplementedChatServiceServer) DeleteChat(context.Context, *DeleteChatRequest) (*DeleteChatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteChat not implemented")
+}
 func (UnimplementedChatServiceServer) mustEmbedUnimplementedChatServiceServer() {}
 
 // UnsafeChatServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -114,19 +348,280 @@ func (x *chatServiceChatStreamServer) Send(m *ChatResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _ChatService_Chat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Chat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/Chat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Chat(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Converse_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ChatServiceServer).Converse(&chatServiceConverseServer{stream})
+}
+
+type ChatService_ConverseServer interface {
+	Send(*ChatResponse) error
+	Recv() (*ConverseRequest, error)
+	grpc.ServerStream
+}
+
+type chatServiceConverseServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceConverseServer) Send(m *ChatResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *chatServiceConverseServer) Recv() (*ConverseRequest, error) {
+	m := new(ConverseRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ChatService_CancelGeneration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelGenerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).CancelGeneration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/CancelGeneration",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).CancelGeneration(ctx, req.(*CancelGenerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Regenerate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RegenerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServiceServer).Regenerate(m, &chatServiceRegenerateServer{stream})
+}
+
+type ChatService_RegenerateServer interface {
+	Send(*ChatResponse) error
+	grpc.ServerStream
+}
+
+type chatServiceRegenerateServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceRegenerateServer) Send(m *ChatResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChatService_EditMessage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EditMessageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServiceServer).EditMessage(m, &chatServiceEditMessageServer{stream})
+}
+
+type ChatService_EditMessageServer interface {
+	Send(*ChatResponse) error
+	grpc.ServerStream
+}
+
+type chatServiceEditMessageServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceEditMessageServer) Send(m *ChatResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChatService_GetChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/GetChat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetChat(ctx, req.(*GetChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/ListMessages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListMessages(ctx, req.(*ListMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListChats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListChats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/ListChats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListChats(ctx, req.(*ListChatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_CloseChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).CloseChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/CloseChat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).CloseChat(ctx, req.(*ChatStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ReopenChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ReopenChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/ReopenChat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ReopenChat(ctx, req.(*ChatStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_DeleteChat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).DeleteChat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.ChatService/DeleteChat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).DeleteChat(ctx, req.(*DeleteChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChatService_ServiceDesc is the grpc.ServiceDesc for ChatService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var ChatService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "pb.ChatService",
 	HandlerType: (*ChatServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Chat",
+			Handler:    _ChatService_Chat_Handler,
+		},
+		{
+			MethodName: "CancelGeneration",
+			Handler:    _ChatService_CancelGeneration_Handler,
+		},
+		{
+			MethodName: "GetChat",
+			Handler:    _ChatService_GetChat_Handler,
+		},
+		{
+			MethodName: "ListMessages",
+			Handler:    _ChatService_ListMessages_Handler,
+		},
+		{
+			MethodName: "ListChats",
+			Handler:    _ChatService_ListChats_Handler,
+		},
+		{
+			MethodName: "CloseChat",
+			Handler:    _ChatService_CloseChat_Handler,
+		},
+		{
+			MethodName: "ReopenChat",
+			Handler:    _ChatService_ReopenChat_Handler,
+		},
+		{
+			MethodName: "DeleteChat",
+			Handler:    _ChatService_DeleteChat_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "ChatStream",
 			Handler:       _ChatService_ChatStream_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Converse",
+			Handler:       _ChatService_Converse_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Regenerate",
+			Handler:       _ChatService_Regenerate_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "EditMessage",
+			Handler:       _ChatService_EditMessage_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "proto/chat.proto",
 }
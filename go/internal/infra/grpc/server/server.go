@@ -1,78 +1,140 @@
 package server
 
 import (
-	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/interceptor"
 	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/pb"
 	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/service"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
+	"log/slog"
 	"net"
+	"sync"
 )
 
+// methodScopes is the scope each RPC requires; unlisted RPCs are denied.
+var methodScopes = map[string]string{
+	"/pb.ChatService/ChatStream":       entity.ScopeChatWrite,
+	"/pb.ChatService/Chat":             entity.ScopeChatWrite,
+	"/pb.ChatService/Converse":         entity.ScopeChatWrite,
+	"/pb.ChatService/CancelGeneration": entity.ScopeChatWrite,
+	"/pb.ChatService/Regenerate":       entity.ScopeChatWrite,
+	"/pb.ChatService/EditMessage":      entity.ScopeChatWrite,
+	"/pb.ChatService/GetChat":          entity.ScopeChatRead,
+	"/pb.ChatService/ListMessages":     entity.ScopeChatRead,
+	"/pb.ChatService/ListChats":        entity.ScopeChatRead,
+	"/pb.ChatService/CloseChat":        entity.ScopeChatWrite,
+	"/pb.ChatService/ReopenChat":       entity.ScopeChatWrite,
+	"/pb.ChatService/DeleteChat":       entity.ScopeChatWrite,
+}
+
 type GRPCServer struct {
-	ChatCompletionStreamUseCase chatcompletionstream.UseCase
-	ChatConfigStream            chatcompletionstream.ConfigInputDTO
-	ChatService                 service.ChatService
-	Port                        string
-	AuthToken                   string
-	StreamChannel               chan chatcompletionstream.OutputDTO
+	ChatService   *service.ChatService
+	Port          string
+	Authenticator *auth.Authenticator
+	Logger        *slog.Logger
+	server        *grpc.Server
+	// handlers counts the running RPC handlers, which Stop does not wait for
+	// once it cancels them
+	handlers sync.WaitGroup
 }
 
 func NewGRPCServer(
-	useCase chatcompletionstream.UseCase,
-	config chatcompletionstream.ConfigInputDTO,
+	chatService *service.ChatService,
 	port string,
-	authToken string,
-	streamChannel chan chatcompletionstream.OutputDTO,
+	authenticator *auth.Authenticator,
+	logger *slog.Logger,
 ) *GRPCServer {
-	chatService := service.NewChatService(useCase, config, streamChannel)
-	return &GRPCServer{
-		ChatCompletionStreamUseCase: useCase,
-		ChatConfigStream:            config,
-		ChatService:                 *chatService,
-		Port:                        port,
-		AuthToken:                   authToken,
-		StreamChannel:               streamChannel,
+	this := &GRPCServer{
+		ChatService:   chatService,
+		Port:          port,
+		Authenticator: authenticator,
+		Logger:        logger,
 	}
-}
-
-func (this *GRPCServer) Start() {
+	// outermost first: the request id is known when logging, and panics are
+	// recovered before the logger sees the call, so they are logged as Internal
 	opts := []grpc.ServerOption{
-		grpc.StreamInterceptor(this.AuthInterceptor),
+		grpc.ChainUnaryInterceptor(
+			this.trackUnary,
+			interceptor.UnaryRequestID,
+			interceptor.UnaryLogging(logger),
+			interceptor.UnaryRecovery(logger),
+			authenticator.UnaryInterceptor(methodScopes),
+		),
+		grpc.ChainStreamInterceptor(
+			this.trackStream,
+			interceptor.StreamRequestID,
+			interceptor.StreamLogging(logger),
+			interceptor.StreamRecovery(logger),
+			authenticator.StreamInterceptor(methodScopes),
+		),
 	}
-	server := grpc.NewServer(opts...)
-	pb.RegisterChatServiceServer(server, &this.ChatService)
+	this.server = grpc.NewServer(opts...)
+	pb.RegisterChatServiceServer(this.server, chatService)
+	return this
+}
 
+// Start serves until Stop is called, which makes it return nil.
+func (this *GRPCServer) Start() error {
 	lis, err := net.Listen("tcp", ":"+this.Port)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
+	return this.server.Serve(lis)
+}
 
-	if err := server.Serve(lis); err != nil {
-		panic(err.Error())
+// Stop stops accepting RPCs and waits for the running ones, streams included,
+// to finish. When ctx expires first, the remaining RPCs are cancelled.
+func (this *GRPCServer) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		this.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		this.server.Stop()
+		return ctx.Err()
 	}
+}
 
+// WaitHandlers waits for the handlers of the RPCs cancelled by Stop to return,
+// as they may still be saving what they did, until ctx expires.
+func (this *GRPCServer) WaitHandlers(ctx context.Context) error {
+	returned := make(chan struct{})
+	go func() {
+		this.handlers.Wait()
+		close(returned)
+	}()
+	select {
+	case <-returned:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (this *GRPCServer) trackUnary(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	this.handlers.Add(1)
+	defer this.handlers.Done()
+	return handler(ctx, req)
 }
 
-func (this *GRPCServer) AuthInterceptor(
-	service any,
-	serverStream grpc.ServerStream,
+func (this *GRPCServer) trackStream(
+	srv any,
+	stream grpc.ServerStream,
 	info *grpc.StreamServerInfo,
 	handler grpc.StreamHandler,
 ) error {
-	ctx := serverStream.Context()
-	meta, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return status.Error(codes.Unauthenticated, "metadata is not provided")
-	}
-	token := meta.Get("authorization")
-	if len(token) == 0 {
-		return status.Error(codes.Unauthenticated, "authorization token is not provided")
-	}
-	if token[0] != this.AuthToken {
-		return status.Error(codes.Unauthenticated, "invalid authorization token")
-	}
-	return handler(service, serverStream)
+	this.handlers.Add(1)
+	defer this.handlers.Done()
+	return handler(srv, stream)
 }
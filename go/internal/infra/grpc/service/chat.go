@@ -1,61 +1,264 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/application/chatturn"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/cancelgeneration"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletion"
 	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/closechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/deletechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/editmessage"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getchat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listchats"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listmessages"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/regenerateanswer"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/reopenchat"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
 	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log/slog"
 )
 
 type ChatService struct {
 	pb.UnimplementedChatServiceServer //gRPC boilerplate
 	ChatCompletionStreamUseCase       chatcompletionstream.UseCase
-	ChatConfigStream                  chatcompletionstream.ConfigInputDTO
-	StreamChannel                     chan chatcompletionstream.OutputDTO
+	ChatCompletionUseCase             *chatcompletion.UseCase
+	ChatConfig                        chatturn.ConfigInputDTO
+	GetChatUseCase                    *getchat.UseCase
+	ListMessagesUseCase               *listmessages.UseCase
+	ListChatsUseCase                  *listchats.UseCase
+	CloseChatUseCase                  *closechat.UseCase
+	ReopenChatUseCase                 *reopenchat.UseCase
+	DeleteChatUseCase                 *deletechat.UseCase
+	CancelGenerationUseCase           *cancelgeneration.UseCase
+	RegenerateAnswerUseCase           *regenerateanswer.UseCase
+	EditMessageUseCase                *editmessage.UseCase
+	Logger                            *slog.Logger
 }
 
-func NewChatService(useCase chatcompletionstream.UseCase, config chatcompletionstream.ConfigInputDTO, streamChannel chan chatcompletionstream.OutputDTO) *ChatService {
+func NewChatService(
+	useCaseStream chatcompletionstream.UseCase,
+	useCase *chatcompletion.UseCase,
+	config chatturn.ConfigInputDTO,
+	getChatUseCase *getchat.UseCase,
+	listMessagesUseCase *listmessages.UseCase,
+	listChatsUseCase *listchats.UseCase,
+	closeChatUseCase *closechat.UseCase,
+	reopenChatUseCase *reopenchat.UseCase,
+	deleteChatUseCase *deletechat.UseCase,
+	cancelGenerationUseCase *cancelgeneration.UseCase,
+	regenerateAnswerUseCase *regenerateanswer.UseCase,
+	editMessageUseCase *editmessage.UseCase,
+	logger *slog.Logger,
+) *ChatService {
 	return &ChatService{
-		ChatCompletionStreamUseCase: useCase,
-		ChatConfigStream:            config,
-		StreamChannel:               streamChannel,
+		ChatCompletionStreamUseCase: useCaseStream,
+		ChatCompletionUseCase:       useCase,
+		ChatConfig:                  config,
+		GetChatUseCase:              getChatUseCase,
+		ListMessagesUseCase:         listMessagesUseCase,
+		ListChatsUseCase:            listChatsUseCase,
+		CloseChatUseCase:            closeChatUseCase,
+		ReopenChatUseCase:           reopenChatUseCase,
+		DeleteChatUseCase:           deleteChatUseCase,
+		CancelGenerationUseCase:     cancelGenerationUseCase,
+		RegenerateAnswerUseCase:     regenerateAnswerUseCase,
+		EditMessageUseCase:          editMessageUseCase,
+		Logger:                      logger,
 	}
 }
 
-func (this *ChatService) ChatStream(req *pb.ChatRequest, stream pb.ChatService_ChatStreamServer) error {
-	chatConfig := chatcompletionstream.ConfigInputDTO{
-		Model:                this.ChatConfigStream.Model,
-		ModelMaxTokens:       this.ChatConfigStream.ModelMaxTokens,
-		Temperature:          this.ChatConfigStream.Temperature,
-		TopP:                 this.ChatConfigStream.TopP,
-		N:                    this.ChatConfigStream.N,
-		Stop:                 this.ChatConfigStream.Stop,
-		MaxTokens:            this.ChatConfigStream.MaxTokens,
-		PresencePenalty:      this.ChatConfigStream.PresencePenalty,
-		FrequencyPenalty:     this.ChatConfigStream.FrequencyPenalty,
-		InitialSystemMessage: this.ChatConfigStream.InitialSystemMessage,
+func (this *ChatService) Chat(ctx context.Context, req *pb.ChatRequest) (*pb.ChatResponse, error) {
+	input := chatcompletion.InputDTO{
+		ChatID:      req.GetChatId(),
+		UserID:      auth.UserIDFromContext(ctx),
+		UserMessage: req.GetUserMessage(),
+		Overrides:   toOverrides(req.GetConfig()),
+		Config:      this.ChatConfig,
+	}
+	output, err := this.ChatCompletionUseCase.Execute(input, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
 	}
+	return &pb.ChatResponse{
+		ChatId:       output.ChatID,
+		UserId:       output.UserID,
+		Content:      output.Content,
+		Sequence:     1,
+		Final:        true,
+		FinishReason: output.FinishReason,
+		MessageId:    output.MessageID,
+		Usage: &pb.Usage{
+			PromptTokens:     int32(output.PromptTokens),
+			CompletionTokens: int32(output.CompletionTokens),
+		},
+	}, nil
+}
 
+func (this *ChatService) ChatStream(req *pb.ChatRequest, stream pb.ChatService_ChatStreamServer) error {
+	ctx := stream.Context()
 	input := &chatcompletionstream.InputDTO{
-		ChatID:      req.GetChatId(),
-		UserID:      req.GetUserId(),
-		UserMessage: req.GetUserMessage(),
-		Config:      chatConfig,
+		ChatID:            req.GetChatId(),
+		UserID:            auth.UserIDFromContext(ctx),
+		UserMessage:       req.GetUserMessage(),
+		Overrides:         toOverrides(req.GetConfig()),
+		CumulativeContent: req.GetCumulativeContent(),
+		Config:            this.ChatConfig,
 	}
 
+	return sendTurn(stream, func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error) {
+		return this.ChatCompletionStreamUseCase.Execute(input, streamChannel, ctx)
+	})
+}
+
+func (this *ChatService) Regenerate(req *pb.RegenerateRequest, stream pb.ChatService_RegenerateServer) error {
+	ctx := stream.Context()
+	input := regenerateanswer.InputDTO{
+		ChatID:            req.GetChatId(),
+		UserID:            auth.UserIDFromContext(ctx),
+		CumulativeContent: req.GetCumulativeContent(),
+	}
+	return sendTurn(stream, func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error) {
+		return this.RegenerateAnswerUseCase.Execute(input, streamChannel, ctx)
+	})
+}
+
+func (this *ChatService) EditMessage(req *pb.EditMessageRequest, stream pb.ChatService_EditMessageServer) error {
 	ctx := stream.Context()
+	input := editmessage.InputDTO{
+		ChatID:            req.GetChatId(),
+		UserID:            auth.UserIDFromContext(ctx),
+		MessageID:         req.GetMessageId(),
+		Content:           req.GetContent(),
+		CumulativeContent: req.GetCumulativeContent(),
+	}
+	return sendTurn(stream, func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error) {
+		return this.EditMessageUseCase.Execute(input, streamChannel, ctx)
+	})
+}
+
+type chatResponseSender interface {
+	Send(*pb.ChatResponse) error
+}
 
+// sendTurn sends a delta frame per chunk run streams, then the final frame
+// with the saved message and its usage.
+func sendTurn(
+	stream chatResponseSender,
+	run func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error),
+) error {
+	// one channel per call, so concurrent streams never see each other's tokens
+	streamChannel := make(chan chatcompletionstream.OutputDTO)
+	done := make(chan struct{})
 	go func() {
-		for msg := range this.StreamChannel {
+		defer close(done)
+		for msg := range streamChannel {
 			stream.Send(&pb.ChatResponse{
-				ChatId:  msg.ChatID,
-				UserId:  msg.UserID,
-				Content: msg.Content,
+				ChatId:       msg.ChatID,
+				UserId:       msg.UserID,
+				Content:      msg.Content,
+				Delta:        msg.Delta,
+				Sequence:     int64(msg.Sequence),
+				GenerationId: msg.GenerationID,
 			})
 		}
 	}()
 
-	_, err := this.ChatCompletionStreamUseCase.Execute(input, ctx)
+	output, err := run(streamChannel)
+	close(streamChannel)
+	<-done
 	if err != nil {
-		return err
+		return toStatusError(err)
+	}
+	return stream.Send(&pb.ChatResponse{
+		ChatId:       output.ChatID,
+		UserId:       output.UserID,
+		Content:      output.Content,
+		Sequence:     int64(output.Sequence),
+		Final:        true,
+		FinishReason: output.FinishReason,
+		MessageId:    output.MessageID,
+		Usage: &pb.Usage{
+			PromptTokens:     int32(output.PromptTokens),
+			CompletionTokens: int32(output.CompletionTokens),
+		},
+		GenerationId: output.GenerationID,
+	})
+}
+
+func (this *ChatService) CancelGeneration(
+	ctx context.Context,
+	req *pb.CancelGenerationRequest,
+) (*pb.CancelGenerationResponse, error) {
+	output, err := this.CancelGenerationUseCase.Execute(cancelgeneration.InputDTO{
+		GenerationID: req.GetGenerationId(),
+		UserID:       auth.UserIDFromContext(ctx),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.CancelGenerationResponse{
+		GenerationId: output.GenerationID,
+	}, nil
+}
+
+func toOverrides(config *pb.ChatConfigOverride) *chatturn.ConfigOverrideInputDTO {
+	if config == nil {
+		return nil
+	}
+	overrides := &chatturn.ConfigOverrideInputDTO{
+		Model:            config.Model,
+		Temperature:      config.Temperature,
+		TopP:             config.TopP,
+		Stop:             config.Stop,
+		PresencePenalty:  config.PresencePenalty,
+		FrequencyPenalty: config.FrequencyPenalty,
+		SystemPrompt:     config.SystemPrompt,
+	}
+	if config.MaxTokens != nil {
+		maxTokens := int(config.GetMaxTokens())
+		overrides.MaxTokens = &maxTokens
+	}
+	return overrides
+}
+
+func toStatusError(err error) error {
+	if errors.Is(err, entity.ErrChatNotFound) || errors.Is(err, gateway.ErrGenerationNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, gateway.ErrInvalidCursor) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, entity.ErrInvalidChatConfig) || errors.Is(err, entity.ErrMessageTooLarge) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, entity.ErrChatForbidden) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, entity.ErrChatClosed) || errors.Is(err, entity.ErrNothingToRegenerate) ||
+		errors.Is(err, entity.ErrMessageNotEditable) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	var conflict *gateway.ConflictError
+	if errors.As(err, &conflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	var quotaExceeded *entity.QuotaExceededError
+	if errors.As(err, &quotaExceeded) {
+		exhausted, detailsErr := status.New(codes.ResourceExhausted, err.Error()).WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(quotaExceeded.RetryAfter),
+		})
+		if detailsErr != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return exhausted.Err()
 	}
-	return nil
+	return status.Error(codes.Internal, err.Error())
 }
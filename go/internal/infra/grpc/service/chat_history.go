@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getchat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listchats"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listmessages"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (this *ChatService) GetChat(ctx context.Context, req *pb.GetChatRequest) (*pb.GetChatResponse, error) {
+	output, err := this.GetChatUseCase.Execute(getchat.InputDTO{
+		ChatID: req.GetChatId(),
+		UserID: auth.UserIDFromContext(ctx),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.GetChatResponse{
+		ChatId:       output.ChatID,
+		UserId:       output.UserID,
+		Status:       output.Status,
+		TokenUsage:   int32(output.TokenUsage),
+		MessageCount: int32(output.MessageCount),
+		Config: &pb.ChatConfig{
+			Model:            output.Config.Model,
+			ModelMaxTokens:   int32(output.Config.ModelMaxTokens),
+			Temperature:      output.Config.Temperature,
+			TopP:             output.Config.TopP,
+			N:                int32(output.Config.N),
+			Stop:             output.Config.Stop,
+			MaxTokens:        int32(output.Config.MaxTokens),
+			PresencePenalty:  output.Config.PresencePenalty,
+			FrequencyPenalty: output.Config.FrequencyPenalty,
+		},
+	}, nil
+}
+
+func (this *ChatService) ListMessages(ctx context.Context, req *pb.ListMessagesRequest) (*pb.ListMessagesResponse, error) {
+	output, err := this.ListMessagesUseCase.Execute(listmessages.InputDTO{
+		ChatID:          req.GetChatId(),
+		UserID:          auth.UserIDFromContext(ctx),
+		IncludeArchived: req.GetIncludeArchived(),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.ListMessagesResponse{
+		ChatId:   output.ChatID,
+		Messages: toPbMessages(output.Messages),
+		Archived: toPbMessages(output.Archived),
+	}, nil
+}
+
+func toPbMessages(outputs []listmessages.MessageOutputDTO) []*pb.Message {
+	messages := make([]*pb.Message, 0, len(outputs))
+	for _, message := range outputs {
+		pbMessage := &pb.Message{
+			Id:        message.ID,
+			Role:      message.Role,
+			Content:   message.Content,
+			Tokens:    int32(message.Tokens),
+			CreatedAt: timestamppb.New(message.CreatedAt),
+			Erased:    message.Erased,
+			Truncated: message.Truncated,
+		}
+		if message.ArchivedAt != nil {
+			pbMessage.ArchivedAt = timestamppb.New(*message.ArchivedAt)
+		}
+		messages = append(messages, pbMessage)
+	}
+	return messages
+}
+
+func (this *ChatService) ListChats(ctx context.Context, req *pb.ListChatsRequest) (*pb.ListChatsResponse, error) {
+	userID := auth.UserIDFromContext(ctx)
+	if req.GetUserId() != "" && req.GetUserId() != userID {
+		return nil, status.Error(codes.PermissionDenied, "chats of other users cannot be listed")
+	}
+	output, err := this.ListChatsUseCase.Execute(listchats.InputDTO{
+		UserID: userID,
+		Cursor: req.GetCursor(),
+		Limit:  int(req.GetLimit()),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	chats := make([]*pb.ChatSummary, 0, len(output.Chats))
+	for _, chat := range output.Chats {
+		chats = append(chats, &pb.ChatSummary{
+			ChatId:     chat.ChatID,
+			Status:     chat.Status,
+			TokenUsage: int32(chat.TokenUsage),
+			Model:      chat.Model,
+			CreatedAt:  timestamppb.New(chat.CreatedAt),
+			UpdatedAt:  timestamppb.New(chat.UpdatedAt),
+		})
+	}
+	return &pb.ListChatsResponse{
+		UserId:     output.UserID,
+		Chats:      chats,
+		NextCursor: output.NextCursor,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/closechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/deletechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/reopenchat"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/pb"
+)
+
+func (this *ChatService) CloseChat(ctx context.Context, req *pb.ChatStatusRequest) (*pb.ChatStatusResponse, error) {
+	output, err := this.CloseChatUseCase.Execute(closechat.InputDTO{
+		ChatID: req.GetChatId(),
+		UserID: auth.UserIDFromContext(ctx),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.ChatStatusResponse{
+		ChatId: output.ChatID,
+		Status: output.Status,
+	}, nil
+}
+
+func (this *ChatService) ReopenChat(ctx context.Context, req *pb.ChatStatusRequest) (*pb.ChatStatusResponse, error) {
+	output, err := this.ReopenChatUseCase.Execute(reopenchat.InputDTO{
+		ChatID: req.GetChatId(),
+		UserID: auth.UserIDFromContext(ctx),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.ChatStatusResponse{
+		ChatId: output.ChatID,
+		Status: output.Status,
+	}, nil
+}
+
+func (this *ChatService) DeleteChat(ctx context.Context, req *pb.DeleteChatRequest) (*pb.DeleteChatResponse, error) {
+	err := this.DeleteChatUseCase.Execute(deletechat.InputDTO{
+		ChatID: req.GetChatId(),
+		UserID: auth.UserIDFromContext(ctx),
+	}, ctx)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.DeleteChatResponse{
+		ChatId: req.GetChatId(),
+	}, nil
+}
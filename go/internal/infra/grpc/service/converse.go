@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/interceptor"
+	"github.com/leo-the-nardo/chatservice/internal/infra/grpc/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+)
+
+// maxPendingFrames bounds the messages and regenerations a Converse client may
+// queue behind the running generation.
+const maxPendingFrames = 8
+
+const converseMethod = "/pb.ChatService/Converse"
+
+// Converse reads frames while a generation runs, so a cancel frame reaches it
+// at once; messages and regenerations wait for it and run in order. Only the
+// generation goroutine sends on the stream, one generation at a time.
+func (this *ChatService) Converse(stream pb.ChatService_ConverseServer) error {
+	ctx := stream.Context()
+	frames := make(chan *pb.ConverseRequest)
+	var recvErr error
+	go func() {
+		defer close(frames)
+		// a panic ends the stream with Internal once the running turn is over
+		defer interceptor.RecoverPanic(ctx, this.Logger, converseMethod, &recvErr)
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					recvErr = err
+				}
+				return
+			}
+			select {
+			case frames <- frame:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var conversation *chatcompletionstream.Conversation
+	var pending []*pb.ConverseRequest
+	var cancel context.CancelFunc
+	finished := make(chan error, 1)
+	closed := false
+	// the generation must be over before returning, as it sends on the stream
+	stop := func() {
+		if cancel != nil {
+			cancel()
+			<-finished
+		}
+	}
+	for {
+		if cancel == nil && len(pending) > 0 {
+			frame := pending[0]
+			pending = pending[1:]
+			if conversation == nil {
+				var err error
+				conversation, err = this.openConversation(frame, ctx)
+				if err != nil {
+					return err
+				}
+			}
+			var turnCtx context.Context
+			turnCtx, cancel = context.WithCancel(ctx)
+			go func() {
+				var err error
+				defer func() {
+					finished <- err
+				}()
+				defer interceptor.RecoverPanic(ctx, this.Logger, converseMethod, &err)
+				err = this.converseTurn(conversation, frame, stream, turnCtx, ctx)
+			}()
+		}
+		if closed && cancel == nil && len(pending) == 0 {
+			// frames is closed once the receiver stopped, so recvErr is set
+			return recvErr
+		}
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				closed = true
+				frames = nil
+				continue
+			}
+			if frame.GetControl().GetAction() == pb.ConverseControl_CANCEL {
+				if cancel != nil {
+					cancel()
+				}
+				continue
+			}
+			if frame.GetMessage() == nil && frame.GetControl().GetAction() != pb.ConverseControl_REGENERATE {
+				stop()
+				return status.Error(codes.InvalidArgument, "unknown converse frame")
+			}
+			if len(pending) == maxPendingFrames {
+				stop()
+				return status.Error(codes.ResourceExhausted, "too many frames waiting for the running generation")
+			}
+			pending = append(pending, frame)
+		case err := <-finished:
+			cancel()
+			cancel = nil
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			stop()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// openConversation opens the chat named by the first frame of the stream; a
+// new chat is only created when the frame names none.
+func (this *ChatService) openConversation(
+	frame *pb.ConverseRequest,
+	ctx context.Context,
+) (*chatcompletionstream.Conversation, error) {
+	req := frame.GetMessage()
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "the first converse frame must be a message")
+	}
+	input := &chatcompletionstream.InputDTO{
+		ChatID:    req.GetChatId(),
+		UserID:    auth.UserIDFromContext(ctx),
+		Overrides: toOverrides(req.GetConfig()),
+		Config:    this.ChatConfig,
+	}
+	var conversation *chatcompletionstream.Conversation
+	var err error
+	if input.ChatID != "" {
+		conversation, err = this.ChatCompletionStreamUseCase.OpenExisting(input, ctx)
+	} else {
+		conversation, err = this.ChatCompletionStreamUseCase.Open(input, ctx)
+	}
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return conversation, nil
+}
+
+// converseTurn runs the generation asked by frame. A generation stopped by a
+// cancel frame ends with a cancelled final frame and keeps the stream open.
+func (this *ChatService) converseTurn(
+	conversation *chatcompletionstream.Conversation,
+	frame *pb.ConverseRequest,
+	stream pb.ChatService_ConverseServer,
+	turnCtx context.Context,
+	ctx context.Context,
+) error {
+	req := frame.GetMessage()
+	if req != nil && req.GetChatId() != "" && req.GetChatId() != conversation.ChatID() {
+		return status.Error(codes.InvalidArgument, "every message of the stream must belong to its chat")
+	}
+	input := &chatcompletionstream.InputDTO{
+		ChatID:            conversation.ChatID(),
+		UserID:            auth.UserIDFromContext(ctx),
+		UserMessage:       req.GetUserMessage(),
+		CumulativeContent: req.GetCumulativeContent(),
+		Config:            this.ChatConfig,
+	}
+
+	streamChannel := make(chan chatcompletionstream.OutputDTO)
+	done := make(chan struct{})
+	sequence := 0
+	go func() {
+		defer close(done)
+		for msg := range streamChannel {
+			sequence = msg.Sequence
+			stream.Send(&pb.ChatResponse{
+				ChatId:       msg.ChatID,
+				UserId:       msg.UserID,
+				Content:      msg.Content,
+				Delta:        msg.Delta,
+				Sequence:     int64(msg.Sequence),
+				GenerationId: msg.GenerationID,
+			})
+		}
+	}()
+
+	var output *chatcompletionstream.OutputDTO
+	var err error
+	if req != nil {
+		output, err = conversation.Send(input, streamChannel, turnCtx)
+	} else {
+		output, err = conversation.Regenerate(input, streamChannel, turnCtx)
+	}
+	close(streamChannel)
+	<-done
+	if err != nil && turnCtx.Err() != nil && ctx.Err() == nil {
+		return stream.Send(&pb.ChatResponse{
+			ChatId:       conversation.ChatID(),
+			UserId:       input.UserID,
+			Sequence:     int64(sequence + 1),
+			Final:        true,
+			FinishReason: gateway.FinishReasonCancelled,
+		})
+	}
+	if err != nil {
+		return toStatusError(err)
+	}
+	return stream.Send(&pb.ChatResponse{
+		ChatId:       output.ChatID,
+		UserId:       output.UserID,
+		Content:      output.Content,
+		Sequence:     int64(output.Sequence),
+		Final:        true,
+		FinishReason: output.FinishReason,
+		MessageId:    output.MessageID,
+		Usage: &pb.Usage{
+			PromptTokens:     int32(output.PromptTokens),
+			CompletionTokens: int32(output.CompletionTokens),
+		},
+		GenerationId: output.GenerationID,
+	})
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"io"
+	"strings"
+)
+
+// FakeProvider answers without any network access. The answer is Reply when
+// set, otherwise an echo of the last user message, so the output is always
+// the same for the same conversation.
+type FakeProvider struct {
+	Reply string
+}
+
+func NewFakeProvider(reply string) *FakeProvider {
+	return &FakeProvider{
+		Reply: reply,
+	}
+}
+
+func (this *FakeProvider) CreateChatCompletion(
+	ctx context.Context,
+	config *entity.ChatConfig,
+	messages []*entity.Message,
+) (*gateway.LLMCompletion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &gateway.LLMCompletion{
+		Content:      this.answer(messages),
+		FinishReason: gateway.FinishReasonStop,
+	}, nil
+}
+
+func (this *FakeProvider) CreateChatCompletionStream(
+	ctx context.Context,
+	config *entity.ChatConfig,
+	messages []*entity.Message,
+) (gateway.LLMStream, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &fakeStream{
+		ctx:    ctx,
+		chunks: strings.SplitAfter(this.answer(messages), " "),
+	}, nil
+}
+
+func (this *FakeProvider) answer(messages []*entity.Message) string {
+	if this.Reply != "" {
+		return this.Reply
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return "echo: " + messages[i].Content
+		}
+	}
+	return "echo"
+}
+
+type fakeStream struct {
+	ctx    context.Context
+	chunks []string
+}
+
+func (this *fakeStream) Recv() (*gateway.LLMChunk, error) {
+	if err := this.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(this.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := &gateway.LLMChunk{Content: this.chunks[0]}
+	this.chunks = this.chunks[1:]
+	if len(this.chunks) == 0 {
+		chunk.FinishReason = gateway.FinishReasonStop
+	}
+	return chunk, nil
+}
+
+func (this *fakeStream) Close() error {
+	return nil
+}
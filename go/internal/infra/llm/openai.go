@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type OpenAIProvider struct {
+	Client *openai.Client
+}
+
+func NewOpenAIProvider(client *openai.Client) *OpenAIProvider {
+	return &OpenAIProvider{
+		Client: client,
+	}
+}
+
+func (this *OpenAIProvider) CreateChatCompletion(
+	ctx context.Context,
+	config *entity.ChatConfig,
+	messages []*entity.Message,
+) (*gateway.LLMCompletion, error) {
+	resp, err := this.Client.CreateChatCompletion(ctx, toRequest(config, messages, false))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("openai returned no choices")
+	}
+	return &gateway.LLMCompletion{
+		Content:      resp.Choices[0].Message.Content,
+		FinishReason: string(resp.Choices[0].FinishReason),
+		Usage: &gateway.LLMUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+func (this *OpenAIProvider) CreateChatCompletionStream(
+	ctx context.Context,
+	config *entity.ChatConfig,
+	messages []*entity.Message,
+) (gateway.LLMStream, error) {
+	stream, err := this.Client.CreateChatCompletionStream(ctx, toRequest(config, messages, true))
+	if err != nil {
+		return nil, err
+	}
+	return &openAIStream{stream: stream}, nil
+}
+
+// openAIStream asks for the usage, which OpenAI sends in a last chunk without
+// choices.
+type openAIStream struct {
+	stream *openai.ChatCompletionStream
+}
+
+func (this *openAIStream) Recv() (*gateway.LLMChunk, error) {
+	for {
+		response, err := this.stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if response.Usage != nil {
+			return &gateway.LLMChunk{
+				Usage: &gateway.LLMUsage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				},
+			}, nil
+		}
+		if len(response.Choices) == 0 {
+			continue
+		}
+		return &gateway.LLMChunk{
+			Content:      response.Choices[0].Delta.Content,
+			FinishReason: string(response.Choices[0].FinishReason),
+		}, nil
+	}
+}
+
+func (this *openAIStream) Close() error {
+	this.stream.Close()
+	return nil
+}
+
+func toRequest(config *entity.ChatConfig, messages []*entity.Message, stream bool) openai.ChatCompletionRequest {
+	var openAIMessages []openai.ChatCompletionMessage
+	for _, msg := range messages {
+		openAIMessages = append(openAIMessages, openai.ChatCompletionMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+	request := openai.ChatCompletionRequest{
+		Model:            config.Model.GetName(),
+		Messages:         openAIMessages,
+		MaxTokens:        config.MaxTokens,
+		Temperature:      config.Temperature,
+		TopP:             config.TopP,
+		N:                config.N,
+		Stop:             config.Stop,
+		PresencePenalty:  config.PresencePenalty,
+		FrequencyPenalty: config.FrequencyPenalty,
+		Stream:           stream,
+	}
+	if stream {
+		request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+	return request
+}
@@ -0,0 +1,62 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the quota counters in the process. Counters are lost on
+// restart and not shared between instances; use the SQL store for that.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	requests map[string]*counter
+	tokens   map[string]*counter
+}
+
+// counter only holds the current window of a user; an older window is reset.
+type counter struct {
+	window time.Time
+	value  int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		requests: make(map[string]*counter),
+		tokens:   make(map[string]*counter),
+	}
+}
+
+func (this *MemoryStore) IncrementRequests(ctx context.Context, userID string, window time.Time) (int, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	current := currentCounter(this.requests, userID, window)
+	current.value++
+	return current.value, nil
+}
+
+func (this *MemoryStore) TokensUsed(ctx context.Context, userID string, window time.Time) (int, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	current, ok := this.tokens[userID]
+	if !ok || !current.window.Equal(window) {
+		return 0, nil
+	}
+	return current.value, nil
+}
+
+func (this *MemoryStore) AddTokens(ctx context.Context, userID string, window time.Time, tokens int) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	currentCounter(this.tokens, userID, window).value += tokens
+	return nil
+}
+
+func currentCounter(counters map[string]*counter, userID string, window time.Time) *counter {
+	current, ok := counters[userID]
+	if !ok || current.window.Before(window) {
+		current = &counter{window: window}
+		counters[userID] = current
+	}
+	return current
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/infra/db"
+	"time"
+)
+
+type APIKeyRepository struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewAPIKeyRepository(database *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		DB:      database,
+		Queries: db.New(database),
+	}
+}
+
+func (this *APIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	scopes, err := json.Marshal(nonNil(key.Scopes))
+	if err != nil {
+		return err
+	}
+	return this.Queries.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		ID:         key.ID,
+		Name:       key.Name,
+		OwnerID:    key.OwnerID,
+		KeyHash:    key.Hash,
+		Scopes:     scopes,
+		ExpiresAt:  toNullTime(key.ExpiresAt),
+		LastUsedAt: toNullTime(key.LastUsedAt),
+		RevokedAt:  toNullTime(key.RevokedAt),
+		CreatedAt:  key.CreatedAt,
+	})
+}
+
+func (this *APIKeyRepository) FindById(ctx context.Context, id string) (*entity.APIKey, error) {
+	dbKey, err := this.Queries.FindAPIKeyById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var scopes []string
+	err = json.Unmarshal(dbKey.Scopes, &scopes)
+	if err != nil {
+		return nil, errors.New("invalid api key scopes: " + err.Error())
+	}
+	return &entity.APIKey{
+		ID:         dbKey.ID,
+		Name:       dbKey.Name,
+		OwnerID:    dbKey.OwnerID,
+		Hash:       dbKey.KeyHash,
+		Scopes:     scopes,
+		ExpiresAt:  fromNullTime(dbKey.ExpiresAt),
+		LastUsedAt: fromNullTime(dbKey.LastUsedAt),
+		RevokedAt:  fromNullTime(dbKey.RevokedAt),
+		CreatedAt:  dbKey.CreatedAt,
+	}, nil
+}
+
+func (this *APIKeyRepository) Revoke(ctx context.Context, key *entity.APIKey) error {
+	return this.Queries.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		RevokedAt: toNullTime(key.RevokedAt),
+		ID:        key.ID,
+	})
+}
+
+func (this *APIKeyRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	return this.Queries.MarkAPIKeyUsed(ctx, db.MarkAPIKeyUsedParams{
+		LastUsedAt: sql.NullTime{Time: usedAt, Valid: true},
+		ID:         id,
+	})
+}
+
+func toNullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *value, Valid: true}
+}
+
+func fromNullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -3,55 +3,80 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
 	"github.com/leo-the-nardo/chatservice/internal/infra/db"
+	"strings"
 	"time"
 )
 
 type ChatRepository struct {
 	DB      *sql.DB
 	Queries *db.Queries
+	Models  *entity.ModelRegistry
 }
 
-func NewChatRepository(database *sql.DB) *ChatRepository {
+func NewChatRepository(database *sql.DB, models *entity.ModelRegistry) *ChatRepository {
 	return &ChatRepository{
 		DB:      database,
 		Queries: db.New(database), //SQLC boilerplate
+		Models:  models,
 	}
 }
 
 func (this *ChatRepository) Create(ctx context.Context, chat *entity.Chat) error {
-	err := this.Queries.CreateChat(ctx, db.CreateChatParams{
-		ID:               chat.ID,
-		UserID:           chat.UserID,
-		InitialMessageID: chat.InitialSystemMessage.ID,
-		Status:           chat.Status,
-		TokenUsage:       int32(chat.TokenUsage),
-		Model:            chat.Config.Model.GetName(),
-		ModelMaxTokens:   int32(chat.Config.Model.GetMaxTokens()),
-		Temperature:      float64(chat.Config.Temperature),
-		TopP:             float64(chat.Config.TopP),
-		N:                int32(chat.Config.N),
-		Stop:             chat.Config.Stop[0],
-		MaxTokens:        int32(chat.Config.MaxTokens),
-		PresencePenalty:  float64(chat.Config.PresencePenalty),
-		FrequencyPenalty: float64(chat.Config.FrequencyPenalty),
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
-	})
+	stop, err := encodeStop(chat.Config.Stop)
+	if err != nil {
+		return err
+	}
+	summarySourceIDs, err := json.Marshal(nonNil(chat.SummarySourceIDs))
 	if err != nil {
 		return err
 	}
-	err = this.Queries.AddMessage(ctx, db.AddMessageParams{
-		ID:        chat.InitialSystemMessage.ID,
-		ChatID:    chat.ID,
-		Content:   chat.InitialSystemMessage.Content,
-		Role:      chat.InitialSystemMessage.Role,
-		Tokens:    int32(chat.InitialSystemMessage.Tokens),
-		CreatedAt: time.Now(),
+	return this.inTx(ctx, func(queries *db.Queries) error {
+		err := queries.CreateChat(ctx, db.CreateChatParams{
+			ID:                 chat.ID,
+			UserID:             chat.UserID,
+			InitialMessageID:   chat.InitialSystemMessage.ID,
+			Status:             chat.Status,
+			TokenUsage:         int32(chat.TokenUsage),
+			Model:              chat.Config.Model.GetName(),
+			ModelMaxTokens:     int32(chat.Config.Model.GetMaxTokens()),
+			Temperature:        float64(chat.Config.Temperature),
+			TopP:               float64(chat.Config.TopP),
+			N:                  int32(chat.Config.N),
+			Stop:               stop,
+			MaxTokens:          int32(chat.Config.MaxTokens),
+			PresencePenalty:    float64(chat.Config.PresencePenalty),
+			FrequencyPenalty:   float64(chat.Config.FrequencyPenalty),
+			TruncationStrategy: chat.Config.TruncationName(),
+			KeepLastTurns:      int32(chat.Config.TruncationKeepTurns()),
+			ReserveReplyTokens: chat.Config.ReserveReplyTokens,
+			Summarize:          chat.Config.Summarize,
+			SummaryMessageID:   summaryMessageID(chat),
+			SummarySourceIds:   summarySourceIDs,
+			CreatedAt:          chat.CreatedAt,
+			UpdatedAt:          chat.UpdatedAt,
+			Version:            int32(chat.Version),
+		})
+		if err != nil {
+			return err
+		}
+		return queries.AddMessage(ctx, db.AddMessageParams{
+			ID:        chat.InitialSystemMessage.ID,
+			ChatID:    chat.ID,
+			Content:   chat.InitialSystemMessage.Content,
+			Role:      chat.InitialSystemMessage.Role,
+			Tokens:    int32(chat.InitialSystemMessage.Tokens),
+			Model:     chat.Config.Model.GetName(),
+			CreatedAt: chat.InitialSystemMessage.CreatedAt,
+			OrderMsg:  0,
+			Erased:    false,
+		})
 	})
-	return err
 }
 
 func (this *ChatRepository) FindById(ctx context.Context, id string) (*entity.Chat, error) {
@@ -73,135 +98,397 @@ func (this *ChatRepository) FindById(ctx context.Context, id string) (*entity.Ch
 	if err != nil {
 		return nil, err
 	}
-	chat := toEntity(dbChat, dbMessages, erasedDBMessages)
-
+	archivedDBMessages, err := this.Queries.FindArchivedMessagesByChatId(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	chat, err := this.toEntity(dbChat, dbMessages, erasedDBMessages)
+	if err != nil {
+		return nil, err
+	}
+	for _, dbMessage := range archivedDBMessages {
+		chat.ArchivedMessages = append(chat.ArchivedMessages, this.toMessage(dbMessage, int(dbChat.ModelMaxTokens)))
+	}
 	return chat, nil
 }
 
+func (this *ChatRepository) ListByUser(
+	ctx context.Context,
+	userID string,
+	cursor string,
+	limit int,
+) ([]gateway.ChatListItem, string, error) {
+	// fetch one extra row to know whether there is a next page
+	var dbChats []db.Chat
+	var err error
+	if cursor == "" {
+		dbChats, err = this.Queries.ListChatsByUser(ctx, db.ListChatsByUserParams{
+			UserID: userID,
+			Limit:  int32(limit + 1),
+		})
+	} else {
+		updatedAt, id, decodeErr := decodeCursor(cursor)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		dbChats, err = this.Queries.ListChatsByUserAfter(ctx, db.ListChatsByUserAfterParams{
+			UserID:          userID,
+			CursorUpdatedAt: updatedAt,
+			CursorID:        id,
+			Limit:           int32(limit + 1),
+		})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(dbChats) > limit {
+		dbChats = dbChats[:limit]
+		last := dbChats[len(dbChats)-1]
+		nextCursor = encodeCursor(last.UpdatedAt, last.ID)
+	}
+	chats := make([]gateway.ChatListItem, 0, len(dbChats))
+	for _, dbChat := range dbChats {
+		chats = append(chats, gateway.ChatListItem{
+			ID:         dbChat.ID,
+			Status:     dbChat.Status,
+			TokenUsage: int(dbChat.TokenUsage),
+			Model:      dbChat.Model,
+			CreatedAt:  dbChat.CreatedAt,
+			UpdatedAt:  dbChat.UpdatedAt,
+		})
+	}
+	return chats, nextCursor, nil
+}
+
+// Save updates the chat row and only touches the messages that changed since
+// the last save: new ones are inserted, the others get their erased flag and
+// order_msg updated in place, and the ones the chat dropped (e.g. a replaced
+// summary) are deleted. Everything runs in a single transaction.
 func (this *ChatRepository) Save(ctx context.Context, chat *entity.Chat) error {
-	params := db.SaveChatParams{
-		ID:               chat.ID,
-		UserID:           chat.UserID,
-		Status:           chat.Status,
-		TokenUsage:       int32(chat.TokenUsage),
-		Model:            chat.Config.Model.GetName(),
-		ModelMaxTokens:   int32(chat.Config.Model.GetMaxTokens()),
-		Temperature:      float64(chat.Config.Temperature),
-		TopP:             float64(chat.Config.TopP),
-		N:                int32(chat.Config.N),
-		Stop:             chat.Config.Stop[0],
-		MaxTokens:        int32(chat.Config.MaxTokens),
-		PresencePenalty:  float64(chat.Config.PresencePenalty),
-		FrequencyPenalty: float64(chat.Config.FrequencyPenalty),
-		UpdatedAt:        time.Now(),
-	}
-	err := this.Queries.SaveChat(
-		ctx,
-		params,
-	)
+	stop, err := encodeStop(chat.Config.Stop)
 	if err != nil {
 		return err
 	}
-	// delete messages
-	err = this.Queries.DeleteChatMessages(ctx, chat.ID)
+	summarySourceIDs, err := json.Marshal(nonNil(chat.SummarySourceIDs))
 	if err != nil {
 		return err
 	}
-	// delete erased messages
-	err = this.Queries.DeleteErasedChatMessages(ctx, chat.ID)
+	updatedAt := time.Now()
+	err = this.inTx(ctx, func(queries *db.Queries) error {
+		rows, err := queries.SaveChat(ctx, db.SaveChatParams{
+			ID:                 chat.ID,
+			UserID:             chat.UserID,
+			InitialMessageID:   initialMessageID(chat),
+			Status:             chat.Status,
+			TokenUsage:         int32(chat.TokenUsage),
+			Model:              chat.Config.Model.GetName(),
+			ModelMaxTokens:     int32(chat.Config.Model.GetMaxTokens()),
+			Temperature:        float64(chat.Config.Temperature),
+			TopP:               float64(chat.Config.TopP),
+			N:                  int32(chat.Config.N),
+			Stop:               stop,
+			MaxTokens:          int32(chat.Config.MaxTokens),
+			PresencePenalty:    float64(chat.Config.PresencePenalty),
+			FrequencyPenalty:   float64(chat.Config.FrequencyPenalty),
+			TruncationStrategy: chat.Config.TruncationName(),
+			KeepLastTurns:      int32(chat.Config.TruncationKeepTurns()),
+			ReserveReplyTokens: chat.Config.ReserveReplyTokens,
+			Summarize:          chat.Config.Summarize,
+			SummaryMessageID:   summaryMessageID(chat),
+			SummarySourceIds:   summarySourceIDs,
+			UpdatedAt:          updatedAt,
+			Version:            int32(chat.Version),
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return &gateway.ConflictError{ChatID: chat.ID, Version: chat.Version}
+		}
+		positions, err := queries.FindMessagePositionsByChatId(ctx, chat.ID)
+		if err != nil {
+			return err
+		}
+		stored := make(map[string]db.FindMessagePositionsByChatIdRow, len(positions))
+		for _, position := range positions {
+			stored[position.ID] = position
+		}
+		// order_msg runs across the three lists, so positions stay unique in the chat:
+		// erased messages first as the oldest, then the window, then archived versions
+		offset := 0
+		err = saveMessages(ctx, queries, chat, chat.ErasedMessages, true, offset, stored)
+		if err != nil {
+			return err
+		}
+		offset += len(chat.ErasedMessages)
+		err = saveMessages(ctx, queries, chat, chat.Messages, false, offset, stored)
+		if err != nil {
+			return err
+		}
+		offset += len(chat.Messages)
+		err = saveMessages(ctx, queries, chat, chat.ArchivedMessages, false, offset, stored)
+		if err != nil {
+			return err
+		}
+		return deleteDroppedMessages(ctx, queries, chat, stored)
+	})
 	if err != nil {
 		return err
 	}
-	// save messages
-	i := 0
-	for _, message := range chat.Messages {
-		err = this.Queries.AddMessage(
-			ctx,
-			db.AddMessageParams{
-				ID:        message.ID,
-				ChatID:    chat.ID,
-				Content:   message.Content,
-				Role:      message.Role,
-				Tokens:    int32(message.Tokens),
-				Model:     chat.Config.Model.GetName(),
-				CreatedAt: message.CreatedAt,
-				OrderMsg:  int32(i),
-				Erased:    false,
-			},
-		)
+	chat.UpdatedAt = updatedAt
+	chat.Version++
+	return nil
+}
+
+func saveMessages(
+	ctx context.Context,
+	queries *db.Queries,
+	chat *entity.Chat,
+	messages []*entity.Message,
+	erased bool,
+	offset int,
+	stored map[string]db.FindMessagePositionsByChatIdRow,
+) error {
+	for i, message := range messages {
+		order := int32(offset + i)
+		position, found := stored[message.ID]
+		if !found {
+			err := queries.AddMessage(ctx, db.AddMessageParams{
+				ID:               message.ID,
+				ChatID:           chat.ID,
+				Content:          message.Content,
+				Role:             message.Role,
+				Tokens:           int32(message.Tokens),
+				Model:            chat.Config.Model.GetName(),
+				CreatedAt:        message.CreatedAt,
+				OrderMsg:         order,
+				Erased:           erased,
+				PromptTokens:     int32(message.PromptTokens),
+				CompletionTokens: int32(message.CompletionTokens),
+				Truncated:        message.Truncated,
+				ArchivedAt:       toNullTime(message.ArchivedAt),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		archived := message.ArchivedAt != nil
+		if position.Erased == erased && position.OrderMsg == order && position.ArchivedAt.Valid == archived {
+			continue
+		}
+		err := queries.UpdateMessagePosition(ctx, db.UpdateMessagePositionParams{
+			Erased:     erased,
+			OrderMsg:   order,
+			ArchivedAt: toNullTime(message.ArchivedAt),
+			ID:         message.ID,
+			ChatID:     chat.ID,
+		})
 		if err != nil {
 			return err
 		}
-		i++
 	}
-	// save erased messages
-	i = 0
+	return nil
+}
+
+// Delete removes the chat; its messages go with it through ON DELETE CASCADE.
+func (this *ChatRepository) Delete(ctx context.Context, id string) error {
+	return this.Queries.DeleteChat(ctx, id)
+}
+
+func deleteDroppedMessages(
+	ctx context.Context,
+	queries *db.Queries,
+	chat *entity.Chat,
+	stored map[string]db.FindMessagePositionsByChatIdRow,
+) error {
+	present := make(map[string]bool, len(chat.Messages)+len(chat.ErasedMessages)+len(chat.ArchivedMessages))
+	for _, message := range chat.Messages {
+		present[message.ID] = true
+	}
 	for _, message := range chat.ErasedMessages {
-		err = this.Queries.AddMessage(
-			ctx,
-			db.AddMessageParams{
-				ID:        message.ID,
-				ChatID:    chat.ID,
-				Content:   message.Content,
-				Role:      message.Role,
-				Tokens:    int32(message.Tokens),
-				Model:     chat.Config.Model.GetName(),
-				CreatedAt: message.CreatedAt,
-				OrderMsg:  int32(i),
-				Erased:    true,
-			},
-		)
+		present[message.ID] = true
+	}
+	for _, message := range chat.ArchivedMessages {
+		present[message.ID] = true
+	}
+	for id := range stored {
+		if present[id] {
+			continue
+		}
+		err := queries.DeleteMessage(ctx, db.DeleteMessageParams{ID: id, ChatID: chat.ID})
 		if err != nil {
 			return err
 		}
-		i++
 	}
 	return nil
+}
+
+func summaryMessageID(chat *entity.Chat) string {
+	if chat.Summary == nil {
+		return ""
+	}
+	return chat.Summary.ID
+}
+
+func initialMessageID(chat *entity.Chat) string {
+	if chat.InitialSystemMessage == nil {
+		return ""
+	}
+	return chat.InitialSystemMessage.ID
+}
 
+func (this *ChatRepository) inTx(ctx context.Context, fn func(queries *db.Queries) error) error {
+	tx, err := this.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(this.Queries.WithTx(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func toEntity(dbChat db.Chat, dbMessages []db.Message, erasedDbMessages []db.Message) *entity.Chat {
+func (this *ChatRepository) toEntity(dbChat db.Chat, dbMessages []db.Message, erasedDbMessages []db.Message) (*entity.Chat, error) {
+	stop, err := decodeStop(dbChat.Stop)
+	if err != nil {
+		return nil, err
+	}
+	truncation, err := entity.NewTruncationStrategy(dbChat.TruncationStrategy, int(dbChat.KeepLastTurns))
+	if err != nil {
+		return nil, err
+	}
+	var summarySourceIDs []string
+	if len(dbChat.SummarySourceIds) > 0 {
+		err = json.Unmarshal(dbChat.SummarySourceIds, &summarySourceIDs)
+		if err != nil {
+			return nil, errors.New("invalid summary source ids: " + err.Error())
+		}
+	}
 	var messages []*entity.Message
 	for _, dbMessage := range dbMessages {
-		messages = append(messages, &entity.Message{
-			ID:        dbMessage.ID,
-			Content:   dbMessage.Content,
-			Role:      dbMessage.Role,
-			CreatedAt: dbMessage.CreatedAt,
-			Model:     entity.NewModel(dbMessage.Model, int(dbChat.ModelMaxTokens)),
-			Tokens:    int(dbMessage.Tokens)},
-		)
+		messages = append(messages, this.toMessage(dbMessage, int(dbChat.ModelMaxTokens)))
 	}
 
 	var erasedMessages []*entity.Message
 	for _, dbMessage := range erasedDbMessages {
-		erasedMessages = append(erasedMessages, &entity.Message{
-			ID:        dbMessage.ID,
-			Content:   dbMessage.Content,
-			Role:      dbMessage.Role,
-			CreatedAt: dbMessage.CreatedAt,
-			Model:     entity.NewModel(dbMessage.Model, int(dbChat.ModelMaxTokens)),
-			Tokens:    int(dbMessage.Tokens)},
-		)
+		erasedMessages = append(erasedMessages, this.toMessage(dbMessage, int(dbChat.ModelMaxTokens)))
+	}
+
+	initialSystemMessage := findMessage(erasedMessages, dbChat.InitialMessageID)
+	if initialSystemMessage == nil {
+		initialSystemMessage = findMessage(messages, dbChat.InitialMessageID)
 	}
 
 	chat := &entity.Chat{
-		ID:             dbChat.ID,
-		UserID:         dbChat.UserID,
-		Status:         dbChat.Status,
-		TokenUsage:     int(dbChat.TokenUsage),
-		Messages:       messages,
-		ErasedMessages: erasedMessages,
+		ID:                   dbChat.ID,
+		UserID:               dbChat.UserID,
+		InitialSystemMessage: initialSystemMessage,
+		Status:               dbChat.Status,
+		TokenUsage:           int(dbChat.TokenUsage),
+		Messages:             messages,
+		ErasedMessages:       erasedMessages,
 		Config: &entity.ChatConfig{
-			Model:            entity.NewModel(dbChat.Model, int(dbChat.ModelMaxTokens)),
-			Temperature:      float32(dbChat.Temperature),
-			TopP:             float32(dbChat.TopP),
-			N:                int(dbChat.N),
-			Stop:             []string{dbChat.Stop},
-			MaxTokens:        int(dbChat.MaxTokens),
-			PresencePenalty:  float32(dbChat.PresencePenalty),
-			FrequencyPenalty: float32(dbChat.FrequencyPenalty),
+			Model:              this.model(dbChat.Model, int(dbChat.ModelMaxTokens)),
+			Temperature:        float32(dbChat.Temperature),
+			TopP:               float32(dbChat.TopP),
+			N:                  int(dbChat.N),
+			Stop:               stop,
+			MaxTokens:          int(dbChat.MaxTokens),
+			PresencePenalty:    float32(dbChat.PresencePenalty),
+			FrequencyPenalty:   float32(dbChat.FrequencyPenalty),
+			Truncation:         truncation,
+			ReserveReplyTokens: dbChat.ReserveReplyTokens,
+			Summarize:          dbChat.Summarize,
 		},
+		CreatedAt:        dbChat.CreatedAt,
+		UpdatedAt:        dbChat.UpdatedAt,
+		Version:          int(dbChat.Version),
+		Summary:          findMessage(messages, dbChat.SummaryMessageID),
+		SummarySourceIDs: summarySourceIDs,
+	}
+	return chat, nil
+}
+
+func (this *ChatRepository) toMessage(dbMessage db.Message, maxTokens int) *entity.Message {
+	return &entity.Message{
+		ID:               dbMessage.ID,
+		Content:          dbMessage.Content,
+		Role:             dbMessage.Role,
+		CreatedAt:        dbMessage.CreatedAt,
+		Model:            this.model(dbMessage.Model, maxTokens),
+		Tokens:           int(dbMessage.Tokens),
+		PromptTokens:     int(dbMessage.PromptTokens),
+		CompletionTokens: int(dbMessage.CompletionTokens),
+		Truncated:        dbMessage.Truncated,
+		ArchivedAt:       fromNullTime(dbMessage.ArchivedAt),
+	}
+}
+
+// model resolves a stored model name through the registry; chats created with a
+// model that was removed from it keep working with the stored context window.
+func (this *ChatRepository) model(name string, maxTokens int) *entity.Model {
+	model, err := this.Models.Get(name)
+	if err != nil {
+		return entity.NewModel(name, maxTokens)
+	}
+	return model
+}
+
+// stop sequences are stored as a JSON array; an empty list is stored as []
+func encodeStop(stop []string) (json.RawMessage, error) {
+	return json.Marshal(nonNil(stop))
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func decodeStop(raw json.RawMessage) ([]string, error) {
+	var stop []string
+	if len(raw) == 0 {
+		return stop, nil
+	}
+	err := json.Unmarshal(raw, &stop)
+	if err != nil {
+		return nil, errors.New("invalid stop sequences: " + err.Error())
+	}
+	return stop, nil
+}
+
+func findMessage(messages []*entity.Message, id string) *entity.Message {
+	for _, message := range messages {
+		if message.ID == id {
+			return message
+		}
+	}
+	return nil
+}
+
+// the cursor is the position of the last chat of a page: its updated_at and id
+func encodeCursor(updatedAt time.Time, id string) string {
+	raw := updatedAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", gateway.ErrInvalidCursor
+	}
+	updatedAtRaw, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return time.Time{}, "", gateway.ErrInvalidCursor
+	}
+	updatedAt, err := time.Parse(time.RFC3339Nano, updatedAtRaw)
+	if err != nil {
+		return time.Time{}, "", gateway.ErrInvalidCursor
 	}
-	return chat
+	return updatedAt, id, nil
 }
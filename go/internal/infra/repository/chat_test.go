@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type storedPosition struct {
+	id       string
+	erased   bool
+	order    int
+	archived bool
+}
+
+type messageWrite struct {
+	kind   string // insert, update or delete
+	id     string
+	erased bool
+	order  int
+}
+
+func testChat(messages []*entity.Message, erased []*entity.Message, archived []*entity.Message) *entity.Chat {
+	return &entity.Chat{
+		ID:               "chat",
+		UserID:           "user",
+		Messages:         messages,
+		ErasedMessages:   erased,
+		ArchivedMessages: archived,
+		Status:           "active",
+		Config:           &entity.ChatConfig{Model: entity.NewModel("gpt-3.5-turbo", 4096)},
+		Version:          3,
+	}
+}
+
+func testMessage(id string) *entity.Message {
+	return &entity.Message{ID: id, Role: "user", Content: id, CreatedAt: time.Now()}
+}
+
+func archivedMessage(id string) *entity.Message {
+	message := testMessage(id)
+	archivedAt := time.Now()
+	message.ArchivedAt = &archivedAt
+	return message
+}
+
+func TestChatRepositorySave(t *testing.T) {
+	tests := []struct {
+		name   string
+		chat   *entity.Chat
+		stored []storedPosition
+		writes []messageWrite
+	}{
+		{
+			name: "inserts new messages after the stored ones",
+			chat: testChat([]*entity.Message{testMessage("s"), testMessage("u1"), testMessage("a1")}, nil, nil),
+			stored: []storedPosition{
+				{id: "s", order: 0},
+				{id: "u1", order: 1},
+			},
+			writes: []messageWrite{
+				{kind: "insert", id: "a1", order: 2},
+			},
+		},
+		{
+			name: "numbers erased messages and the window with one running position",
+			chat: testChat(
+				[]*entity.Message{testMessage("s"), testMessage("a1"), testMessage("u2")},
+				[]*entity.Message{testMessage("u1")},
+				nil,
+			),
+			stored: []storedPosition{
+				{id: "s", order: 0},
+				{id: "u1", order: 1},
+				{id: "a1", order: 2},
+			},
+			writes: []messageWrite{
+				{kind: "update", id: "u1", erased: true, order: 0},
+				{kind: "update", id: "s", order: 1},
+				{kind: "insert", id: "u2", order: 3},
+			},
+		},
+		{
+			name: "archives a replaced answer after the window",
+			chat: testChat(
+				[]*entity.Message{testMessage("s"), testMessage("u1"), testMessage("a2")},
+				nil,
+				[]*entity.Message{archivedMessage("a1")},
+			),
+			stored: []storedPosition{
+				{id: "s", order: 0},
+				{id: "u1", order: 1},
+				{id: "a1", order: 2},
+			},
+			writes: []messageWrite{
+				{kind: "insert", id: "a2", order: 2},
+				{kind: "update", id: "a1", order: 3},
+			},
+		},
+		{
+			name: "leaves unchanged messages alone",
+			chat: testChat(
+				[]*entity.Message{testMessage("s"), testMessage("u1")},
+				nil,
+				[]*entity.Message{archivedMessage("a1")},
+			),
+			stored: []storedPosition{
+				{id: "s", order: 0},
+				{id: "u1", order: 1},
+				{id: "a1", order: 2, archived: true},
+			},
+		},
+		{
+			name: "deletes the messages the chat dropped",
+			chat: testChat([]*entity.Message{testMessage("s"), testMessage("u1")}, nil, nil),
+			stored: []storedPosition{
+				{id: "s", order: 0},
+				{id: "summary", order: 1},
+				{id: "u1", order: 2},
+			},
+			writes: []messageWrite{
+				{kind: "update", id: "u1", order: 1},
+				{kind: "delete", id: "summary"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer database.Close()
+			repository := NewChatRepository(database, nil)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE chats SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+			rows := sqlmock.NewRows([]string{"id", "erased", "order_msg", "archived_at"})
+			for _, position := range tt.stored {
+				var archivedAt interface{}
+				if position.archived {
+					archivedAt = time.Now()
+				}
+				rows.AddRow(position.id, position.erased, position.order, archivedAt)
+			}
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT id, erased, order_msg, archived_at FROM messages")).
+				WithArgs("chat").
+				WillReturnRows(rows)
+			for _, write := range tt.writes {
+				switch write.kind {
+				case "insert":
+					mock.ExpectExec(regexp.QuoteMeta("INSERT INTO messages")).
+						WithArgs(
+							write.id, "chat", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+							write.erased, int64(write.order),
+							sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+						).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				case "update":
+					mock.ExpectExec(regexp.QuoteMeta("UPDATE messages SET erased = ?, order_msg = ?, archived_at = ? WHERE id = ? AND chat_id = ?")).
+						WithArgs(write.erased, int64(write.order), sqlmock.AnyArg(), write.id, "chat").
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				case "delete":
+					mock.ExpectExec(regexp.QuoteMeta("DELETE FROM messages WHERE id = ? AND chat_id = ?")).
+						WithArgs(write.id, "chat").
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+			}
+			mock.ExpectCommit()
+
+			err = repository.Save(context.Background(), tt.chat)
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if tt.chat.Version != 4 {
+				t.Errorf("Version = %d, want 4", tt.chat.Version)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestChatRepositorySaveConflict(t *testing.T) {
+	database, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer database.Close()
+	repository := NewChatRepository(database, nil)
+	chat := testChat([]*entity.Message{testMessage("s")}, nil, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE chats SET")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = repository.Save(context.Background(), chat)
+	var conflict *gateway.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("err = %v, want a ConflictError", err)
+	}
+	if chat.Version != 3 {
+		t.Errorf("Version = %d, want it unchanged", chat.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/leo-the-nardo/chatservice/internal/infra/db"
+	"time"
+)
+
+const (
+	requestsCounter = "requests"
+	tokensCounter   = "tokens"
+)
+
+// QuotaRepository keeps the quota counters in MySQL so every instance of the
+// service shares them. Only the current window of each counter is kept.
+type QuotaRepository struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewQuotaRepository(database *sql.DB) *QuotaRepository {
+	return &QuotaRepository{
+		DB:      database,
+		Queries: db.New(database),
+	}
+}
+
+func (this *QuotaRepository) IncrementRequests(ctx context.Context, userID string, window time.Time) (int, error) {
+	count, err := this.increment(ctx, userID, requestsCounter, window, 1)
+	return int(count), err
+}
+
+func (this *QuotaRepository) TokensUsed(ctx context.Context, userID string, window time.Time) (int, error) {
+	value, err := this.Queries.GetQuotaCounter(ctx, db.GetQuotaCounterParams{
+		UserID:      userID,
+		Counter:     tokensCounter,
+		WindowStart: window,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return int(value), err
+}
+
+func (this *QuotaRepository) AddTokens(ctx context.Context, userID string, window time.Time, tokens int) error {
+	_, err := this.increment(ctx, userID, tokensCounter, window, tokens)
+	return err
+}
+
+// increment adds value to the counter and reads it back in one transaction, so
+// the row lock taken by the upsert makes the returned count exact.
+func (this *QuotaRepository) increment(
+	ctx context.Context,
+	userID string,
+	counter string,
+	window time.Time,
+	value int,
+) (int64, error) {
+	tx, err := this.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	queries := this.Queries.WithTx(tx)
+	err = queries.DeleteQuotaCountersBefore(ctx, db.DeleteQuotaCountersBeforeParams{
+		UserID:      userID,
+		Counter:     counter,
+		WindowStart: window,
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	err = queries.IncrementQuotaCounter(ctx, db.IncrementQuotaCounterParams{
+		UserID:      userID,
+		Counter:     counter,
+		WindowStart: window,
+		Value:       int64(value),
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	total, err := queries.GetQuotaCounter(ctx, db.GetQuotaCounterParams{
+		UserID:      userID,
+		Counter:     counter,
+		WindowStart: window,
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return total, tx.Commit()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/infra/db"
+	"time"
+)
+
+type UsageRepository struct {
+	DB      *sql.DB
+	Queries *db.Queries
+}
+
+func NewUsageRepository(database *sql.DB) *UsageRepository {
+	return &UsageRepository{
+		DB:      database,
+		Queries: db.New(database),
+	}
+}
+
+func (this *UsageRepository) Record(ctx context.Context, event *entity.UsageEvent) error {
+	return this.Queries.CreateUsageEvent(ctx, db.CreateUsageEventParams{
+		ID:               event.ID,
+		UserID:           event.UserID,
+		ChatID:           event.ChatID,
+		Model:            event.Model,
+		PromptTokens:     int32(event.PromptTokens),
+		CompletionTokens: int32(event.CompletionTokens),
+		Cost:             event.Cost,
+		CreatedAt:        event.CreatedAt,
+	})
+}
+
+func (this *UsageRepository) SummarizeByUser(
+	ctx context.Context,
+	userID string,
+	from time.Time,
+	to time.Time,
+) ([]*entity.UsageSummary, error) {
+	rows, err := this.Queries.SummarizeUsageByUser(ctx, db.SummarizeUsageByUserParams{
+		UserID:   userID,
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]*entity.UsageSummary, 0, len(rows))
+	for _, row := range rows {
+		summaries = append(summaries, &entity.UsageSummary{
+			Model:            row.Model,
+			Requests:         int(row.Requests),
+			PromptTokens:     int(row.PromptTokens),
+			CompletionTokens: int(row.CompletionTokens),
+			Cost:             row.Cost,
+		})
+	}
+	return summaries, nil
+}
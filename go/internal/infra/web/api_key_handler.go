@@ -0,0 +1,50 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/createapikey"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/revokeapikey"
+	"net/http"
+)
+
+// APIKeyHandler serves the admin endpoints of API keys; routes must be
+// guarded by the admin scope.
+type APIKeyHandler struct {
+	CreateAPIKeyUseCase *createapikey.UseCase
+	RevokeAPIKeyUseCase *revokeapikey.UseCase
+}
+
+func NewWebAPIKeyHandler(
+	createAPIKeyUseCase *createapikey.UseCase,
+	revokeAPIKeyUseCase *revokeapikey.UseCase,
+) *APIKeyHandler {
+	return &APIKeyHandler{
+		CreateAPIKeyUseCase: createAPIKeyUseCase,
+		RevokeAPIKeyUseCase: revokeAPIKeyUseCase,
+	}
+}
+
+func (this *APIKeyHandler) Create(res http.ResponseWriter, req *http.Request) {
+	var input createapikey.InputDTO
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		http.Error(res, "invalid json", http.StatusBadRequest)
+		return
+	}
+	result, err := this.CreateAPIKeyUseCase.Execute(input, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusCreated, result)
+}
+
+func (this *APIKeyHandler) Revoke(res http.ResponseWriter, req *http.Request) {
+	result, err := this.RevokeAPIKeyUseCase.Execute(revokeapikey.InputDTO{ID: chi.URLParam(req, "id")}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
@@ -2,22 +2,22 @@ package web
 
 import (
 	"encoding/json"
+	"github.com/leo-the-nardo/chatservice/internal/application/chatturn"
 	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletion"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
 	"io"
 	"net/http"
 )
 
 type ChatGPTHandler struct {
 	CompletionUseCase *chatcompletion.UseCase
-	Config            chatcompletion.ConfigInputDTO
-	AuthToken         string
+	Config            chatturn.ConfigInputDTO
 }
 
-func NewWebChatGPTHandler(useCase *chatcompletion.UseCase, config chatcompletion.ConfigInputDTO, authToken string) *ChatGPTHandler {
+func NewWebChatGPTHandler(useCase *chatcompletion.UseCase, config chatturn.ConfigInputDTO) *ChatGPTHandler {
 	return &ChatGPTHandler{
 		CompletionUseCase: useCase,
 		Config:            config,
-		AuthToken:         authToken,
 	}
 }
 
@@ -26,10 +26,6 @@ func (this *ChatGPTHandler) Handle(res http.ResponseWriter, req *http.Request) {
 		res.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	if req.Header.Get("Authorization") != this.AuthToken {
-		res.WriteHeader(http.StatusUnauthorized)
-		return
-	}
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -45,10 +41,11 @@ func (this *ChatGPTHandler) Handle(res http.ResponseWriter, req *http.Request) {
 		http.Error(res, err.Error(), http.StatusBadRequest)
 		return
 	}
+	inputDTO.UserID = auth.UserIDFromContext(req.Context())
 	inputDTO.Config = this.Config
 	result, err := this.CompletionUseCase.Execute(inputDTO, req.Context())
 	if err != nil {
-		http.Error(res, err.Error(), http.StatusInternalServerError)
+		writeError(res, err)
 		return
 	}
 	res.WriteHeader(http.StatusOK)
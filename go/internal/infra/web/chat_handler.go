@@ -0,0 +1,141 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getchat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getusage"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listchats"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/listmessages"
+	"github.com/leo-the-nardo/chatservice/internal/domain/entity"
+	"github.com/leo-the-nardo/chatservice/internal/domain/gateway"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type ChatHandler struct {
+	GetChatUseCase      *getchat.UseCase
+	ListMessagesUseCase *listmessages.UseCase
+	ListChatsUseCase    *listchats.UseCase
+}
+
+func NewWebChatHandler(
+	getChatUseCase *getchat.UseCase,
+	listMessagesUseCase *listmessages.UseCase,
+	listChatsUseCase *listchats.UseCase,
+) *ChatHandler {
+	return &ChatHandler{
+		GetChatUseCase:      getChatUseCase,
+		ListMessagesUseCase: listMessagesUseCase,
+		ListChatsUseCase:    listChatsUseCase,
+	}
+}
+
+func (this *ChatHandler) GetChat(res http.ResponseWriter, req *http.Request) {
+	result, err := this.GetChatUseCase.Execute(getchat.InputDTO{
+		ChatID: chi.URLParam(req, "id"),
+		UserID: auth.UserIDFromContext(req.Context()),
+	}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
+
+func (this *ChatHandler) ListMessages(res http.ResponseWriter, req *http.Request) {
+	result, err := this.ListMessagesUseCase.Execute(listmessages.InputDTO{
+		ChatID:          chi.URLParam(req, "id"),
+		UserID:          auth.UserIDFromContext(req.Context()),
+		IncludeArchived: req.URL.Query().Get("include_archived") == "true",
+	}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
+
+func (this *ChatHandler) ListChats(res http.ResponseWriter, req *http.Request) {
+	userID, ok := pathUser(res, req)
+	if !ok {
+		return
+	}
+	input := listchats.InputDTO{
+		UserID: userID,
+		Cursor: req.URL.Query().Get("cursor"),
+	}
+	if limit := req.URL.Query().Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(res, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		input.Limit = parsed
+	}
+	result, err := this.ListChatsUseCase.Execute(input, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
+
+// pathUser returns the user_id of the path, which must be the authenticated
+// user: users only see their own resources.
+func pathUser(res http.ResponseWriter, req *http.Request) (string, bool) {
+	userID := chi.URLParam(req, "user_id")
+	if userID != auth.UserIDFromContext(req.Context()) {
+		http.Error(res, "forbidden", http.StatusForbidden)
+		return "", false
+	}
+	return userID, true
+}
+
+func writeJSON(res http.ResponseWriter, statusCode int, body any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(statusCode)
+	json.NewEncoder(res).Encode(body)
+}
+
+func writeError(res http.ResponseWriter, err error) {
+	var quotaExceeded *entity.QuotaExceededError
+	if errors.As(err, &quotaExceeded) {
+		retryAfter := int(math.Ceil(quotaExceeded.RetryAfter.Seconds()))
+		res.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	http.Error(res, err.Error(), errorStatus(err))
+}
+
+// errorStatus maps the errors of the use cases to the HTTP status reporting them.
+func errorStatus(err error) int {
+	if errors.Is(err, entity.ErrChatNotFound) || errors.Is(err, entity.ErrAPIKeyNotFound) ||
+		errors.Is(err, gateway.ErrGenerationNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, gateway.ErrInvalidCursor) || errors.Is(err, getusage.ErrInvalidPeriod) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, entity.ErrChatForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, entity.ErrInvalidChatConfig) || errors.Is(err, entity.ErrInvalidAPIKeySpec) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, entity.ErrMessageTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var conflict *gateway.ConflictError
+	if errors.Is(err, entity.ErrChatClosed) || errors.As(err, &conflict) ||
+		errors.Is(err, entity.ErrNothingToRegenerate) || errors.Is(err, entity.ErrMessageNotEditable) {
+		return http.StatusConflict
+	}
+	var quotaExceeded *entity.QuotaExceededError
+	if errors.As(err, &quotaExceeded) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/closechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/deletechat"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/reopenchat"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"net/http"
+)
+
+type ChatStatusHandler struct {
+	CloseChatUseCase  *closechat.UseCase
+	ReopenChatUseCase *reopenchat.UseCase
+	DeleteChatUseCase *deletechat.UseCase
+}
+
+func NewWebChatStatusHandler(
+	closeChatUseCase *closechat.UseCase,
+	reopenChatUseCase *reopenchat.UseCase,
+	deleteChatUseCase *deletechat.UseCase,
+) *ChatStatusHandler {
+	return &ChatStatusHandler{
+		CloseChatUseCase:  closeChatUseCase,
+		ReopenChatUseCase: reopenChatUseCase,
+		DeleteChatUseCase: deleteChatUseCase,
+	}
+}
+
+func (this *ChatStatusHandler) Close(res http.ResponseWriter, req *http.Request) {
+	result, err := this.CloseChatUseCase.Execute(closechat.InputDTO{
+		ChatID: chi.URLParam(req, "id"),
+		UserID: auth.UserIDFromContext(req.Context()),
+	}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
+
+func (this *ChatStatusHandler) Reopen(res http.ResponseWriter, req *http.Request) {
+	result, err := this.ReopenChatUseCase.Execute(reopenchat.InputDTO{
+		ChatID: chi.URLParam(req, "id"),
+		UserID: auth.UserIDFromContext(req.Context()),
+	}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
+
+func (this *ChatStatusHandler) Delete(res http.ResponseWriter, req *http.Request) {
+	err := this.DeleteChatUseCase.Execute(deletechat.InputDTO{
+		ChatID: chi.URLParam(req, "id"),
+		UserID: auth.UserIDFromContext(req.Context()),
+	}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,216 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/leo-the-nardo/chatservice/internal/application/chatturn"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/cancelgeneration"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/chatcompletionstream"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/editmessage"
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/regenerateanswer"
+	"github.com/leo-the-nardo/chatservice/internal/infra/auth"
+	"io"
+	"net/http"
+)
+
+type ChatStreamHandler struct {
+	CompletionStreamUseCase *chatcompletionstream.UseCase
+	CancelGenerationUseCase *cancelgeneration.UseCase
+	RegenerateAnswerUseCase *regenerateanswer.UseCase
+	EditMessageUseCase      *editmessage.UseCase
+	Config                  chatturn.ConfigInputDTO
+}
+
+func NewWebChatStreamHandler(
+	useCase *chatcompletionstream.UseCase,
+	cancelGenerationUseCase *cancelgeneration.UseCase,
+	regenerateAnswerUseCase *regenerateanswer.UseCase,
+	editMessageUseCase *editmessage.UseCase,
+	config chatturn.ConfigInputDTO,
+) *ChatStreamHandler {
+	return &ChatStreamHandler{
+		CompletionStreamUseCase: useCase,
+		CancelGenerationUseCase: cancelGenerationUseCase,
+		RegenerateAnswerUseCase: regenerateAnswerUseCase,
+		EditMessageUseCase:      editMessageUseCase,
+		Config:                  config,
+	}
+}
+
+type errorEvent struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// Handle streams the answer as Server-Sent Events: "start" with the generation
+// id, a "delta" event per chunk, then "done" with the saved message and its
+// usage, or "error". Errors raised before the generation starts are plain HTTP
+// errors. The request context is passed down, so a client disconnect cancels
+// the upstream completion; the partial answer is saved as truncated, as when
+// the generation is cancelled by id.
+func (this *ChatStreamHandler) Handle(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		http.Error(res, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !json.Valid(body) {
+		http.Error(res, "invalid json", http.StatusBadRequest)
+		return
+	}
+	var inputDTO chatcompletionstream.InputDTO
+	err = json.Unmarshal(body, &inputDTO)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inputDTO.UserID = auth.UserIDFromContext(req.Context())
+	inputDTO.Config = this.Config
+	streamEvents(res, flusher, func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error) {
+		return this.CompletionStreamUseCase.Execute(&inputDTO, streamChannel, req.Context())
+	})
+}
+
+// Regenerate archives the last answer of the chat and streams a new one as
+// Handle does. The body is optional.
+func (this *ChatStreamHandler) Regenerate(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		http.Error(res, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var inputDTO regenerateanswer.InputDTO
+	if len(body) > 0 {
+		if !json.Valid(body) {
+			http.Error(res, "invalid json", http.StatusBadRequest)
+			return
+		}
+		err = json.Unmarshal(body, &inputDTO)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	inputDTO.ChatID = chi.URLParam(req, "id")
+	inputDTO.UserID = auth.UserIDFromContext(req.Context())
+	streamEvents(res, flusher, func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error) {
+		return this.RegenerateAnswerUseCase.Execute(inputDTO, streamChannel, req.Context())
+	})
+}
+
+// EditMessage replaces the last user message of the chat, archiving it and its
+// answer, and streams the answer to the new content as Handle does.
+func (this *ChatStreamHandler) EditMessage(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		http.Error(res, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !json.Valid(body) {
+		http.Error(res, "invalid json", http.StatusBadRequest)
+		return
+	}
+	var inputDTO editmessage.InputDTO
+	err = json.Unmarshal(body, &inputDTO)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inputDTO.ChatID = chi.URLParam(req, "id")
+	inputDTO.MessageID = chi.URLParam(req, "message_id")
+	inputDTO.UserID = auth.UserIDFromContext(req.Context())
+	streamEvents(res, flusher, func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error) {
+		return this.EditMessageUseCase.Execute(inputDTO, streamChannel, req.Context())
+	})
+}
+
+// streamEvents writes the chunks run streams as "delta" events, then "done"
+// or "error".
+func streamEvents(
+	res http.ResponseWriter,
+	flusher http.Flusher,
+	run func(streamChannel chan<- chatcompletionstream.OutputDTO) (*chatcompletionstream.OutputDTO, error),
+) {
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		res.Header().Set("Content-Type", "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+	}
+
+	// the channel is drained until run returns, even when writes fail
+	streamChannel := make(chan chatcompletionstream.OutputDTO)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range streamChannel {
+			start()
+			event := "delta"
+			if msg.Sequence == 0 {
+				event = "start"
+			}
+			writeEvent(res, event, msg)
+			flusher.Flush()
+		}
+	}()
+
+	result, err := run(streamChannel)
+	close(streamChannel)
+	<-done
+	if err != nil && !started {
+		writeError(res, err)
+		return
+	}
+	start()
+	if err != nil {
+		writeEvent(res, "error", errorEvent{Error: err.Error(), Status: errorStatus(err)})
+	} else {
+		writeEvent(res, "done", result)
+	}
+	flusher.Flush()
+}
+
+func writeEvent(res http.ResponseWriter, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(errorEvent{Error: err.Error(), Status: http.StatusInternalServerError})
+		event = "error"
+	}
+	fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+// Cancel stops the generation named in the path; its stream ends with a "done"
+// event whose finish_reason is "cancelled".
+func (this *ChatStreamHandler) Cancel(res http.ResponseWriter, req *http.Request) {
+	result, err := this.CancelGenerationUseCase.Execute(cancelgeneration.InputDTO{
+		GenerationID: chi.URLParam(req, "id"),
+		UserID:       auth.UserIDFromContext(req.Context()),
+	}, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"github.com/leo-the-nardo/chatservice/internal/application/usecase/getusage"
+	"net/http"
+	"time"
+)
+
+type UsageHandler struct {
+	GetUsageUseCase *getusage.UseCase
+}
+
+func NewWebUsageHandler(getUsageUseCase *getusage.UseCase) *UsageHandler {
+	return &UsageHandler{
+		GetUsageUseCase: getUsageUseCase,
+	}
+}
+
+// GetUsage accepts from and to as RFC 3339 timestamps or plain dates.
+func (this *UsageHandler) GetUsage(res http.ResponseWriter, req *http.Request) {
+	userID, ok := pathUser(res, req)
+	if !ok {
+		return
+	}
+	input := getusage.InputDTO{UserID: userID}
+	var err error
+	input.From, err = parseTime(req.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(res, "invalid from", http.StatusBadRequest)
+		return
+	}
+	input.To, err = parseTime(req.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(res, "invalid to", http.StatusBadRequest)
+		return
+	}
+	result, err := this.GetUsageUseCase.Execute(input, req.Context())
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	writeJSON(res, http.StatusOK, result)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
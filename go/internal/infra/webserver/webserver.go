@@ -1,36 +1,105 @@
 package webserver
 
 import (
+	"context"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"net/http"
+	"sync"
 )
 
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
+
 type WebServer struct {
 	Router        chi.Router
 	Handlers      map[string]http.HandlerFunc
+	Routes        []route
+	Middlewares   []func(http.Handler) http.Handler
 	WebServerPort string
+	server        *http.Server
+	// handlers counts the running handlers, which a closed server does not
+	// wait for
+	handlers sync.WaitGroup
 }
 
 func NewWebServer(webServerPort string) *WebServer {
-	return &WebServer{
+	router := chi.NewRouter()
+	this := &WebServer{
 		WebServerPort: webServerPort,
 		Handlers:      make(map[string]http.HandlerFunc),
-		Router:        chi.NewRouter(),
+		Router:        router,
 	}
+	this.server = &http.Server{Addr: webServerPort, Handler: this.track(router)}
+	return this
 }
 
 func (this *WebServer) AddHandler(path string, handler http.HandlerFunc) {
 	this.Handlers[path] = handler
 }
 
-func (this *WebServer) Start() {
+// AddMethodHandler registers a handler bound to a single HTTP method, so the
+// same path can serve e.g. GET and DELETE through different handlers.
+func (this *WebServer) AddMethodHandler(method string, path string, handler http.HandlerFunc) {
+	this.Routes = append(this.Routes, route{method: method, path: path, handler: handler})
+}
+
+// AddMiddleware wraps every handler, in the order the middlewares are added.
+func (this *WebServer) AddMiddleware(middleware func(http.Handler) http.Handler) {
+	this.Middlewares = append(this.Middlewares, middleware)
+}
+
+// Start serves until Shutdown is called, which makes it return nil.
+func (this *WebServer) Start() error {
 	this.Router.Use(middleware.Logger)
+	this.Router.Use(this.Middlewares...)
 	for path, handler := range this.Handlers { //register handlers
 		this.Router.HandleFunc(path, handler)
 	}
-	err := http.ListenAndServe(this.WebServerPort, this.Router)
+	for _, route := range this.Routes {
+		this.Router.MethodFunc(route.method, route.path, route.handler)
+	}
+	err := this.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for the active requests to
+// finish. When ctx expires first, the remaining connections are closed.
+func (this *WebServer) Shutdown(ctx context.Context) error {
+	err := this.server.Shutdown(ctx)
 	if err != nil {
-		panic(err.Error())
+		this.server.Close()
 	}
+	return err
+}
+
+// WaitHandlers waits for the handlers whose connections Shutdown closed to
+// return, as they may still be saving what they did, until ctx expires.
+func (this *WebServer) WaitHandlers(ctx context.Context) error {
+	returned := make(chan struct{})
+	go func() {
+		this.handlers.Wait()
+		close(returned)
+	}()
+	select {
+	case <-returned:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (this *WebServer) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		this.handlers.Add(1)
+		defer this.handlers.Done()
+		next.ServeHTTP(res, req)
+	})
 }
@@ -0,0 +1 @@
+DROP INDEX idx_chats_user_id_updated_at ON chats;
@@ -0,0 +1 @@
+CREATE INDEX idx_chats_user_id_updated_at ON chats (user_id, updated_at, id);
@@ -0,0 +1 @@
+ALTER TABLE chats ADD COLUMN version INT NOT NULL DEFAULT 0;
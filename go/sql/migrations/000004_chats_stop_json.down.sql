@@ -0,0 +1,5 @@
+ALTER TABLE chats ADD COLUMN stop_single VARCHAR(20) NULL;
+UPDATE chats SET stop_single = COALESCE(JSON_UNQUOTE(JSON_EXTRACT(stop, '$[0]')), '');
+ALTER TABLE chats DROP COLUMN stop;
+ALTER TABLE chats RENAME COLUMN stop_single TO stop;
+ALTER TABLE chats MODIFY stop VARCHAR(20) NOT NULL;
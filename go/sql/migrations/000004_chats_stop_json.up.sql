@@ -0,0 +1,5 @@
+ALTER TABLE chats ADD COLUMN stop_sequences JSON NULL;
+UPDATE chats SET stop_sequences = IF(stop = '', JSON_ARRAY(), JSON_ARRAY(stop));
+ALTER TABLE chats DROP COLUMN stop;
+ALTER TABLE chats RENAME COLUMN stop_sequences TO stop;
+ALTER TABLE chats MODIFY stop JSON NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE chats
+    DROP COLUMN truncation_strategy,
+    DROP COLUMN keep_last_turns,
+    DROP COLUMN reserve_reply_tokens;
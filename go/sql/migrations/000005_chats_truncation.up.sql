@@ -0,0 +1,4 @@
+ALTER TABLE chats
+    ADD COLUMN truncation_strategy VARCHAR(20) NOT NULL DEFAULT 'drop_oldest',
+    ADD COLUMN keep_last_turns SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN reserve_reply_tokens BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE chats
+    DROP COLUMN summarize,
+    DROP COLUMN summary_message_id,
+    DROP COLUMN summary_source_ids;
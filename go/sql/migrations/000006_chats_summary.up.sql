@@ -0,0 +1,4 @@
+ALTER TABLE chats
+    ADD COLUMN summarize BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN summary_message_id VARCHAR(36) NOT NULL DEFAULT '',
+    ADD COLUMN summary_source_ids JSON NULL;
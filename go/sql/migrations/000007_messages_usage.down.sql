@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    DROP COLUMN prompt_tokens,
+    DROP COLUMN completion_tokens;
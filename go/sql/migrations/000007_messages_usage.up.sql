@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    ADD COLUMN prompt_tokens INT NOT NULL DEFAULT 0,
+    ADD COLUMN completion_tokens INT NOT NULL DEFAULT 0;
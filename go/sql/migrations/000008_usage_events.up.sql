@@ -0,0 +1,12 @@
+-- no foreign key on chat_id: billed usage outlives deleted chats
+CREATE TABLE IF NOT EXISTS `usage_events` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    chat_id VARCHAR(36) NOT NULL,
+    model VARCHAR(50) NOT NULL,
+    prompt_tokens INT NOT NULL,
+    completion_tokens INT NOT NULL,
+    cost DECIMAL(20,8) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    INDEX idx_usage_events_user_id_created_at (user_id, created_at)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `quota_counters` (
+    user_id VARCHAR(36) NOT NULL,
+    counter VARCHAR(20) NOT NULL,
+    window_start TIMESTAMP NOT NULL,
+    value BIGINT NOT NULL,
+    PRIMARY KEY (user_id, counter, window_start)
+);
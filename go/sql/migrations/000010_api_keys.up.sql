@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    id VARCHAR(36) NOT NULL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    owner_id VARCHAR(36) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes JSON NOT NULL,
+    expires_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    INDEX idx_api_keys_owner_id (owner_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE messages
+    ADD COLUMN truncated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- archived messages are the versions replaced by a regeneration or an edit
+ALTER TABLE messages
+    ADD COLUMN archived_at TIMESTAMP NULL;
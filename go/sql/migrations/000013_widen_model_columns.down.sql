@@ -0,0 +1,9 @@
+ALTER TABLE chats
+    MODIFY COLUMN token_usage SMALLINT NOT NULL,
+    MODIFY COLUMN model VARCHAR(20) NOT NULL,
+    MODIFY COLUMN model_max_tokens SMALLINT NOT NULL,
+    MODIFY COLUMN max_tokens SMALLINT NOT NULL;
+
+ALTER TABLE messages
+    MODIFY COLUMN tokens SMALLINT NOT NULL,
+    MODIFY COLUMN model VARCHAR(20) NOT NULL;
@@ -0,0 +1,10 @@
+-- the model registry allows context windows past SMALLINT and longer model names
+ALTER TABLE chats
+    MODIFY COLUMN token_usage INT NOT NULL,
+    MODIFY COLUMN model VARCHAR(50) NOT NULL,
+    MODIFY COLUMN model_max_tokens INT NOT NULL,
+    MODIFY COLUMN max_tokens INT NOT NULL;
+
+ALTER TABLE messages
+    MODIFY COLUMN tokens INT NOT NULL,
+    MODIFY COLUMN model VARCHAR(50) NOT NULL;
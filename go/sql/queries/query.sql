@@ -1,6 +1,20 @@
 -- name: FindChatById :one
 SELECT * FROM chats WHERE id = ?;
 
+-- name: ListChatsByUser :many
+SELECT * FROM chats
+WHERE user_id = ?
+ORDER BY updated_at DESC, id DESC
+LIMIT ?;
+
+-- name: ListChatsByUserAfter :many
+SELECT * FROM chats
+WHERE user_id = sqlc.arg(user_id)
+  AND (updated_at < sqlc.arg(cursor_updated_at)
+    OR (updated_at = sqlc.arg(cursor_updated_at) AND id < sqlc.arg(cursor_id)))
+ORDER BY updated_at DESC, id DESC
+LIMIT ?;
+
 -- name: CreateChat :exec
 INSERT INTO chats (id,
                    user_id,
@@ -17,8 +31,15 @@ INSERT INTO chats (id,
                    presence_penalty,
                    frequency_penalty,
                    created_at,
-                   updated_at)
-VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);
+                   updated_at,
+                   version,
+                   truncation_strategy,
+                   keep_last_turns,
+                   reserve_reply_tokens,
+                   summarize,
+                   summary_message_id,
+                   summary_source_ids)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);
 
 -- name: AddMessage :exec
 INSERT INTO messages (id,
@@ -29,17 +50,24 @@ INSERT INTO messages (id,
                       model,
                       erased,
                       order_msg,
-                      created_at)
-VALUES (?,?,?,?,?,?,?,?,?);
+                      created_at,
+                      prompt_tokens,
+                      completion_tokens,
+                      truncated,
+                      archived_at)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?);
 
 -- name: FindMessagesByChatId :many
-SELECT * FROM messages WHERE erased=0 AND chat_id = ? ORDER BY order_msg ASC;
+SELECT * FROM messages WHERE erased=0 AND archived_at IS NULL AND chat_id = ? ORDER BY order_msg ASC;
 
 -- name: FindErasedMessagesByChatId :many
-SELECT * FROM messages WHERE erased=1 AND chat_id = ? ORDER BY order_msg ASC;
+SELECT * FROM messages WHERE erased=1 AND archived_at IS NULL AND chat_id = ? ORDER BY order_msg ASC;
+
+-- name: FindArchivedMessagesByChatId :many
+SELECT * FROM messages WHERE archived_at IS NOT NULL AND chat_id = ? ORDER BY order_msg ASC;
 
 
--- name: SaveChat :exec
+-- name: SaveChat :execrows
 UPDATE chats SET
                  user_id = ?,
                  initial_message_id = ?,
@@ -54,11 +82,79 @@ UPDATE chats SET
                  max_tokens = ?,
                  presence_penalty = ?,
                  frequency_penalty = ?,
-                 updated_at = ?
-    WHERE id = ?;
+                 truncation_strategy = ?,
+                 keep_last_turns = ?,
+                 reserve_reply_tokens = ?,
+                 summarize = ?,
+                 summary_message_id = ?,
+                 summary_source_ids = ?,
+                 updated_at = ?,
+                 version = version + 1
+    WHERE id = ? AND version = ?;
+
+-- name: FindMessagePositionsByChatId :many
+SELECT id, erased, order_msg, archived_at FROM messages WHERE chat_id = ?;
+
+-- name: UpdateMessagePosition :exec
+UPDATE messages SET erased = ?, order_msg = ?, archived_at = ? WHERE id = ? AND chat_id = ?;
+
+-- name: DeleteMessage :exec
+DELETE FROM messages WHERE id = ? AND chat_id = ?;
+
+-- name: DeleteChat :exec
+DELETE FROM chats WHERE id = ?;
+-- name: CreateUsageEvent :exec
+INSERT INTO usage_events (id,
+                          user_id,
+                          chat_id,
+                          model,
+                          prompt_tokens,
+                          completion_tokens,
+                          cost,
+                          created_at)
+VALUES (?,?,?,?,?,?,?,?);
+
+-- name: SummarizeUsageByUser :many
+SELECT model,
+       COUNT(*) AS requests,
+       CAST(SUM(prompt_tokens) AS SIGNED) AS prompt_tokens,
+       CAST(SUM(completion_tokens) AS SIGNED) AS completion_tokens,
+       CAST(SUM(cost) AS DECIMAL(20,8)) AS cost
+FROM usage_events
+WHERE user_id = sqlc.arg(user_id)
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at < sqlc.arg(to_time)
+GROUP BY model
+ORDER BY model;
+
+-- name: IncrementQuotaCounter :exec
+INSERT INTO quota_counters (user_id, counter, window_start, value)
+VALUES (?,?,?,?)
+ON DUPLICATE KEY UPDATE value = value + VALUES(value);
+
+-- name: GetQuotaCounter :one
+SELECT value FROM quota_counters WHERE user_id = ? AND counter = ? AND window_start = ?;
+
+-- name: DeleteQuotaCountersBefore :exec
+DELETE FROM quota_counters WHERE user_id = ? AND counter = ? AND window_start < ?;
+
+-- name: CreateAPIKey :exec
+INSERT INTO api_keys (id,
+                      name,
+                      owner_id,
+                      key_hash,
+                      scopes,
+                      expires_at,
+                      last_used_at,
+                      revoked_at,
+                      created_at)
+VALUES (?,?,?,?,?,?,?,?,?);
+
+-- name: FindAPIKeyById :one
+SELECT * FROM api_keys WHERE id = ?;
 
--- name: DeleteChatMessages :exec
-DELETE FROM messages WHERE chat_id = ?;
+-- name: RevokeAPIKey :exec
+UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL;
 
--- name: DeleteErasedChatMessages :exec
-DELETE FROM messages WHERE erased=1 and chat_id = ?;
\ No newline at end of file
+-- name: MarkAPIKeyUsed :exec
+UPDATE api_keys SET last_used_at = ? WHERE id = ?;
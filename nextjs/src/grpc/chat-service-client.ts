@@ -1,10 +1,26 @@
 import { ChatServiceClient as GrpcChatServiceClient } from "./rpc/pb/ChatService"
 import { chatClient } from "@/grpc/client"
 import * as grpc from "@grpc/grpc-js"
+import { createHmac } from "crypto"
 
-export class ChatServiceClient {
-  private static AUTH_KEY = "123456"
+// signs a short-lived HS256 token for the user, verified by the chat service
+// with its JWT_SECRET
+function signToken(userId: string) {
+  const encode = (value: object) =>
+    Buffer.from(JSON.stringify(value)).toString("base64url")
+  const now = Math.floor(Date.now() / 1000)
+  const unsigned = `${encode({ alg: "HS256", typ: "JWT" })}.${encode({
+    sub: userId,
+    iat: now,
+    exp: now + 60,
+  })}`
+  const signature = createHmac("sha256", process.env.CHAT_SERVICE_JWT_SECRET!)
+    .update(unsigned)
+    .digest("base64url")
+  return `${unsigned}.${signature}`
+}
 
+export class ChatServiceClient {
   constructor(private chatClient: GrpcChatServiceClient) {}
 
   chatStream(data: {
@@ -13,13 +29,15 @@ export class ChatServiceClient {
     message: string
   }) {
     const metadata = new grpc.Metadata()
-    metadata.set("authorization", ChatServiceClient.AUTH_KEY)
+    metadata.set("authorization", `Bearer ${signToken(data.user_id)}`)
 
     const stream = this.chatClient.chatStream(
       {
         chatId: data.chat_id!,
         userId: data.user_id,
         userMessage: data.message,
+        // the page renders the answer so far from every frame
+        cumulativeContent: true,
       },
       metadata,
     )
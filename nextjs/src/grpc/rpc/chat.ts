@@ -11,6 +11,7 @@ export interface ProtoGrpcType {
   pb: {
     ChatRequest: MessageTypeDefinition
     ChatResponse: MessageTypeDefinition
+    Usage: MessageTypeDefinition
     ChatService: SubtypeConstructor<typeof grpc.Client, _pb_ChatServiceClient> & { service: _pb_ChatServiceDefinition }
   }
 }
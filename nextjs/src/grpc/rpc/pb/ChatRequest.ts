@@ -5,6 +5,7 @@ export interface ChatRequest {
   'chatId'?: (string);
   'userId'?: (string);
   'userMessage'?: (string);
+  'cumulativeContent'?: (boolean);
   '_chatId'?: "chatId";
 }
 
@@ -12,5 +13,6 @@ export interface ChatRequest__Output {
   'chatId'?: (string);
   'userId': (string);
   'userMessage': (string);
+  'cumulativeContent': (boolean);
   '_chatId': "chatId";
 }
@@ -1,14 +1,28 @@
 // Original file: proto/chat.proto
 
+import type { Usage as _pb_Usage, Usage__Output as _pb_Usage__Output } from '../pb/Usage';
+import type { Long } from '@grpc/proto-loader';
 
 export interface ChatResponse {
   'chatId'?: (string);
   'userId'?: (string);
   'content'?: (string);
+  'delta'?: (string);
+  'sequence'?: (number | string | Long);
+  'final'?: (boolean);
+  'finishReason'?: (string);
+  'messageId'?: (string);
+  'usage'?: (_pb_Usage | null);
 }
 
 export interface ChatResponse__Output {
   'chatId': (string);
   'userId': (string);
   'content': (string);
+  'delta': (string);
+  'sequence': (Long);
+  'final': (boolean);
+  'finishReason': (string);
+  'messageId': (string);
+  'usage': (_pb_Usage__Output | null);
 }
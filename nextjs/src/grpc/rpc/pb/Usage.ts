@@ -0,0 +1,12 @@
+// Original file: proto/chat.proto
+
+
+export interface Usage {
+  'promptTokens'?: (number);
+  'completionTokens'?: (number);
+}
+
+export interface Usage__Output {
+  'promptTokens': (number);
+  'completionTokens': (number);
+}